@@ -4,7 +4,10 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
+	_ "time/tzdata" // zone names for -z on systems without a zone database
 
 	"github.com/developertyrone/picgroup/pkg/organizer"
 )
@@ -15,16 +18,56 @@ var (
 	BuildTime = "unknown"
 )
 
+// stringList collects the values of a flag that may be given more than once.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "apply" {
+		apply(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "undo" {
+		undo(os.Args[2:])
+		return
+	}
+
 	// Define command-line flags
 	versionFlag := flag.Bool("version", false, "Print version information")
-	srcPath := flag.String("d", "", "Directory path (absolute path)")
-	folderFormat := flag.String("f", "ymd", "Folder format (ymd/ym)")
+	folderFormat := flag.String("f", "ymd", "Folder format (ymd/ym or a template such as {year}/{month:02}-{monthname})")
 	generated := flag.String("t", "generated", "Generated folder name")
+	outputRoot := flag.String("o", "", "Output folder for the organized library (default: the generated folder inside -d)")
 	groupMode := flag.String("g", "move", "Grouping mode (move/copy)")
 	copyMode := flag.String("m", "seq", "File copy mode (seq/con)")
 	verboseMode := flag.String("v", "0", "Verbose mode (0/1)")
 	workerCount := flag.Int("w", runtime.NumCPU(), "Number of worker threads (for concurrent mode)")
+	sidecarMode := flag.String("c", organizer.SidecarMove, "Sidecar handling (move/drop)")
+	xmpPrecedence := flag.String("x", organizer.XMPPrecedenceSidecar, "Which dates win between XMP sidecars and embedded metadata (sidecar/embedded)")
+	timezone := flag.String("z", "local", "Bucket by local time at the shooting location (local) or by a time zone (UTC, Europe/Berlin, +09:00)")
+	clockTable := flag.String("k", "", "Clock correction table for cameras with a wrong clock")
+	collisionPolicy := flag.String("e", organizer.CollisionRename, "When the destination exists (skip/overwrite/rename/keep-newer/skip-identical)")
+	dedupMode := flag.String("u", organizer.DedupOff, "Duplicates already in the generated folder (off/skip/link/quarantine)")
+	perceptualHash := flag.String("n", organizer.PerceptualOff, "Near-duplicate detection with a perceptual hash (off/dhash/phash)")
+	similarity := flag.Int("i", organizer.DefaultSimilarityThreshold, "Number of the 64 hash bits near-duplicates may differ in")
+	renameFormat := flag.String("r", "", "Rename template such as {date:20060102_150405}_{camera}_{seq:04}{ext} (keep names when empty; {seq} counts per day within the run)")
+	language := flag.String("l", organizer.DefaultLanguage, "Language of month and weekday names in folder templates ("+strings.Join(organizer.Languages(), ", ")+")")
+	journalPath := flag.String("j", "", "Journal of the changes for undo (default: a new file in .picgroup inside the library)")
+	resume := flag.Bool("resume", false, "Continue an interrupted run from its checkpoint, skipping the folders and files it finished (near-duplicates with -n are only looked for among the files still to do)")
+	planPath := flag.String("plan", "", "Dry run: write what would be done to this plan file (.json or .csv) and change nothing")
+	dateChain := flag.String("s", strings.Join(organizer.DefaultDateChain, ","), "Date sources tried in order (comma-separated; xmp reads XMP sidecars, see -x)")
+
+	var srcPaths stringList
+	flag.Var(&srcPaths, "d", "Directory path (absolute path; repeatable to organize several sources into one library)")
+	var filenamePatterns stringList
+	flag.Var(&filenamePatterns, "p", "Extra filename date regex with named groups year, month, day (repeatable)")
 
 	flag.Parse()
 
@@ -34,6 +77,162 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Execute the organizer with parsed flag values
-	organizer.Execute(*srcPath, *folderFormat, *generated, *copyMode, *verboseMode, *groupMode, *workerCount)
+	chain, err := organizer.ParseDateChain(*dateChain)
+	if err != nil {
+		fmt.Println("Invalid date sources:", err)
+		os.Exit(1)
+	}
+
+	if _, err := organizer.ParseFolderFormat(*folderFormat); err != nil {
+		fmt.Println("Invalid folder format:", err)
+		os.Exit(1)
+	}
+
+	policy, err := organizer.ParseCollisionPolicy(*collisionPolicy)
+	if err != nil {
+		fmt.Println("Invalid collision policy:", err)
+		os.Exit(1)
+	}
+
+	dedup, err := organizer.ParseDedupMode(*dedupMode)
+	if err != nil {
+		fmt.Println("Invalid dedup mode:", err)
+		os.Exit(1)
+	}
+
+	hash, err := organizer.ParsePerceptualHash(*perceptualHash)
+	if err != nil {
+		fmt.Println("Invalid perceptual hash:", err)
+		os.Exit(1)
+	}
+	if *similarity < 0 || *similarity > 64 {
+		fmt.Println("Invalid similarity threshold:", *similarity)
+		os.Exit(1)
+	}
+
+	if *renameFormat != "" {
+		if _, err := organizer.ParseRenameFormat(*renameFormat); err != nil {
+			fmt.Println("Invalid rename format:", err)
+			os.Exit(1)
+		}
+	}
+
+	sidecars, err := organizer.ParseSidecarMode(*sidecarMode)
+	if err != nil {
+		fmt.Println("Invalid sidecar mode:", err)
+		os.Exit(1)
+	}
+
+	precedence, err := organizer.ParseXMPPrecedence(*xmpPrecedence)
+	if err != nil {
+		fmt.Println("Invalid XMP precedence:", err)
+		os.Exit(1)
+	}
+
+	lang, err := organizer.ParseLanguage(*language)
+	if err != nil {
+		fmt.Println("Invalid language:", err)
+		os.Exit(1)
+	}
+
+	location, err := organizer.ParseTimezone(*timezone)
+	if err != nil {
+		fmt.Println("Invalid time zone:", err)
+		os.Exit(1)
+	}
+
+	var clockRules []*organizer.ClockRule
+	if *clockTable != "" {
+		if clockRules, err = organizer.LoadClockTable(*clockTable); err != nil {
+			fmt.Println("Invalid clock table:", err)
+			os.Exit(1)
+		}
+	}
+
+	patterns := make([]organizer.FilenamePattern, 0, len(filenamePatterns))
+	for _, expr := range filenamePatterns {
+		pattern, err := organizer.ParseFilenamePattern(expr)
+		if err != nil {
+			fmt.Println("Invalid filename pattern:", err)
+			os.Exit(1)
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	// The first source holds the generated folder unless -o is given
+	var srcPath string
+	if len(srcPaths) > 0 {
+		srcPath = srcPaths[0]
+	}
+
+	// Run the organizer with parsed flag values
+	org := organizer.NewOrganizer(srcPath, *folderFormat, *generated, *copyMode, *verboseMode, *groupMode)
+	org.DateChain = chain
+	org.FilenamePatterns = patterns
+	org.SidecarMode = sidecars
+	org.XMPPrecedence = precedence
+	org.BucketLocation = location
+	org.ClockRules = clockRules
+	org.Language = lang
+	org.RenameFormat = *renameFormat
+	org.OutputRoot = *outputRoot
+	if len(srcPaths) > 1 {
+		org.Sources = srcPaths[1:]
+	}
+	org.CollisionPolicy = policy
+	org.DedupMode = dedup
+	org.PerceptualHash = hash
+	org.SimilarityThreshold = *similarity
+	org.DryRun = *planPath != ""
+	org.PlanPath = *planPath
+	org.JournalPath = *journalPath
+	org.Resume = *resume
+	org.Run(*workerCount)
+}
+
+// apply executes a plan file written by a dry run: picgroup apply [-v 1] [-j journal] plan.json
+func apply(args []string) {
+	flags := flag.NewFlagSet("apply", flag.ExitOnError)
+	verboseMode := flags.String("v", "0", "Verbose mode (0/1)")
+	journalPath := flags.String("j", "", "Journal of the changes for undo (default: the plan's name with .journal.jsonl)")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Println("Usage: picgroup apply [-v 1] [-j JOURNAL] PLAN")
+		os.Exit(1)
+	}
+	planPath := flags.Arg(0)
+
+	actions, err := organizer.ReadPlan(planPath)
+	if err != nil {
+		fmt.Println("Invalid plan:", err)
+		os.Exit(1)
+	}
+	org := organizer.NewOrganizer("", "ymd", "generated", "seq", *verboseMode, "move")
+	org.JournalPath = *journalPath
+	if org.JournalPath == "" {
+		org.JournalPath = strings.TrimSuffix(planPath, filepath.Ext(planPath)) + ".journal.jsonl"
+	}
+	if err := org.ApplyPlan(actions); err != nil {
+		fmt.Println("Plan applied with errors")
+		os.Exit(1)
+	}
+	fmt.Printf("Applied %d actions\n", len(actions))
+}
+
+// undo reverses the run recorded in a journal: picgroup undo [-v 1] journal.jsonl
+func undo(args []string) {
+	flags := flag.NewFlagSet("undo", flag.ExitOnError)
+	verboseMode := flags.String("v", "0", "Verbose mode (0/1)")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Println("Usage: picgroup undo [-v 1] JOURNAL")
+		os.Exit(1)
+	}
+
+	org := organizer.NewOrganizer("", "ymd", "generated", "seq", *verboseMode, "move")
+	if err := org.Undo(flags.Arg(0)); err != nil {
+		fmt.Println("Undo failed:", err)
+		os.Exit(1)
+	}
+	fmt.Println("Undone", flags.Arg(0))
 }
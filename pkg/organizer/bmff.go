@@ -0,0 +1,214 @@
+package organizer
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// --- ISO base media file format (ISO-BMFF / QuickTime) box reader ---
+
+// errBoxNotFound is returned when a box path does not exist in a file.
+var errBoxNotFound = errors.New("box not found")
+
+// maxBoxRead limits how much of a single box is loaded into memory.
+const maxBoxRead = 16 * 1024 * 1024
+
+// bmffBox describes one box (atom) of an ISO-BMFF or QuickTime file.
+type bmffBox struct {
+	Type       string
+	UUID       []byte // extended type for "uuid" boxes
+	Offset     int64  // offset of the box header
+	HeaderSize int64
+	Size       int64 // total size including the header
+}
+
+// dataOffset returns the offset of the box payload.
+func (b bmffBox) dataOffset() int64 {
+	return b.Offset + b.HeaderSize
+}
+
+// dataEnd returns the offset just past the box.
+func (b bmffBox) dataEnd() int64 {
+	return b.Offset + b.Size
+}
+
+// readBMFFBoxes lists the boxes found between start and end.
+func readBMFFBoxes(r io.ReadSeeker, start, end int64) ([]bmffBox, error) {
+	var boxes []bmffBox
+	header := make([]byte, 16)
+
+	for offset := start; offset+8 <= end; {
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return boxes, err
+		}
+		if _, err := io.ReadFull(r, header[:8]); err != nil {
+			return boxes, err
+		}
+
+		box := bmffBox{
+			Type:       string(header[4:8]),
+			Offset:     offset,
+			HeaderSize: 8,
+			Size:       int64(binary.BigEndian.Uint32(header[:4])),
+		}
+
+		switch box.Size {
+		case 0:
+			// The box extends to the end of its parent.
+			box.Size = end - offset
+		case 1:
+			if _, err := io.ReadFull(r, header[8:16]); err != nil {
+				return boxes, err
+			}
+			box.Size = int64(binary.BigEndian.Uint64(header[8:16]))
+			box.HeaderSize = 16
+		}
+
+		if box.Type == "uuid" {
+			box.UUID = make([]byte, 16)
+			if _, err := io.ReadFull(r, box.UUID); err != nil {
+				return boxes, err
+			}
+			box.HeaderSize += 16
+		}
+
+		// Compared with the room left, a 64-bit size near the maximum cannot overflow the check.
+		if box.Size < box.HeaderSize || box.Size > end-offset {
+			return boxes, fmt.Errorf("invalid size %d for box %q at offset %d", box.Size, box.Type, offset)
+		}
+
+		boxes = append(boxes, box)
+		offset += box.Size
+	}
+	return boxes, nil
+}
+
+// bmffChildren lists the children of a container box, skipping the
+// version/flags header of full boxes such as "meta".
+func bmffChildren(r io.ReadSeeker, parent bmffBox) ([]bmffBox, error) {
+	start := parent.dataOffset()
+	if parent.Type == "meta" {
+		// ISO "meta" is a full box, QuickTime "meta" is a plain container.
+		// A QuickTime meta starts straight away with a child box such as "hdlr".
+		peek := make([]byte, 8)
+		if _, err := r.Seek(start, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(r, peek); err != nil {
+			return nil, err
+		}
+		if string(peek[4:8]) != "hdlr" {
+			start += 4
+		}
+	}
+	return readBMFFBoxes(r, start, parent.dataEnd())
+}
+
+// fileSize returns the size of the content behind r.
+func fileSize(r io.Seeker) (int64, error) {
+	return r.Seek(0, io.SeekEnd)
+}
+
+// findBMFFBox follows a path of box types from the top level of the file.
+func findBMFFBox(r io.ReadSeeker, path ...string) (bmffBox, error) {
+	size, err := fileSize(r)
+	if err != nil {
+		return bmffBox{}, err
+	}
+	boxes, err := readBMFFBoxes(r, 0, size)
+	if err != nil && len(boxes) == 0 {
+		return bmffBox{}, err
+	}
+	return findBMFFPath(r, boxes, path...)
+}
+
+// findBMFFPath follows a path of box types starting from the given siblings.
+func findBMFFPath(r io.ReadSeeker, boxes []bmffBox, path ...string) (bmffBox, error) {
+	for i, boxType := range path {
+		found := -1
+		for j, box := range boxes {
+			if box.Type == boxType {
+				found = j
+				break
+			}
+		}
+		if found < 0 {
+			return bmffBox{}, errBoxNotFound
+		}
+		if i == len(path)-1 {
+			return boxes[found], nil
+		}
+		children, err := bmffChildren(r, boxes[found])
+		if err != nil && len(children) == 0 {
+			return bmffBox{}, err
+		}
+		boxes = children
+	}
+	return bmffBox{}, errBoxNotFound
+}
+
+// readBoxData loads the payload of a box.
+func readBoxData(r io.ReadSeeker, box bmffBox) ([]byte, error) {
+	size := box.dataEnd() - box.dataOffset()
+	if size > maxBoxRead {
+		return nil, fmt.Errorf("box %q too large: %d bytes", box.Type, size)
+	}
+	if _, err := r.Seek(box.dataOffset(), io.SeekStart); err != nil {
+		return nil, err
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// ftypBrands parses the payload of an "ftyp" box into its brands, major brand first.
+func ftypBrands(data []byte) []string {
+	if len(data) < 8 {
+		return nil
+	}
+	brands := []string{string(data[0:4])}
+	for i := 8; i+4 <= len(data); i += 4 {
+		brands = append(brands, string(data[i:i+4]))
+	}
+	return brands
+}
+
+// byteParser reads big-endian integers of variable width and remembers the first error.
+type byteParser struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+// uint reads an n-byte big-endian unsigned integer. A zero width reads nothing and returns 0.
+func (p *byteParser) uint(n int) uint64 {
+	if p.err != nil || n == 0 {
+		return 0
+	}
+	if p.pos+n > len(p.data) {
+		p.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	var v uint64
+	for _, b := range p.data[p.pos : p.pos+n] {
+		v = v<<8 | uint64(b)
+	}
+	p.pos += n
+	return v
+}
+
+// skip advances over n bytes.
+func (p *byteParser) skip(n int) {
+	if p.err != nil {
+		return
+	}
+	if p.pos+n > len(p.data) {
+		p.err = io.ErrUnexpectedEOF
+		return
+	}
+	p.pos += n
+}
@@ -0,0 +1,256 @@
+package organizer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// --- Checkpoints and resumed runs ---
+
+// CheckpointFile is the checkpoint of the current run in JournalFolder. It is removed when the
+// run completes.
+const CheckpointFile = "checkpoint.jsonl"
+
+// Checkpoint operations.
+const (
+	checkpointStart = "start" // a file is being copied or moved
+	checkpointDone  = "done"  // a file is finished
+	checkpointDir   = "dir"   // a folder and everything below it is finished
+)
+
+// checkpointEntry records progress of a run, one JSON object per line.
+type checkpointEntry struct {
+	Op          string `json:"op"`
+	Path        string `json:"path"`
+	Destination string `json:"destination,omitempty"` // where a started file is going
+	Existed     bool   `json:"existed,omitempty"`     // the destination existed when the file was started,
+	Size        int64  `json:"size,omitempty"`        // with this size
+	ModTime     int64  `json:"mtime,omitempty"`       // and modification time in Unix nanoseconds
+	Day         string `json:"day,omitempty"`         // day and capture time a finished file was numbered by
+	Time        string `json:"time,omitempty"`
+}
+
+// checkpointPath returns the checkpoint file in the library.
+func (o *Organizer) checkpointPath() string {
+	return path.Join(o.destRoot(), JournalFolder, CheckpointFile)
+}
+
+// startCheckpoint opens the checkpoint of a run. A resumed run first reads what the interrupted
+// run finished and cleans up after the files it was in the middle of; any other run starts a
+// new checkpoint.
+func (o *Organizer) startCheckpoint() {
+	checkpointPath := o.checkpointPath()
+	flags := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	var started map[string]checkpointEntry
+	if o.Resume {
+		var err error
+		started, err = o.loadCheckpoint(checkpointPath)
+		switch {
+		case os.IsNotExist(err):
+			fmt.Println("No interrupted run to resume, starting from the beginning")
+		case err != nil:
+			log.Printf("Error reading checkpoint, starting from the beginning: %v", err)
+			o.finishedDirs, o.finishedFiles, o.pending, started = nil, nil, nil, nil
+			flags |= os.O_TRUNC
+		default:
+			fmt.Printf("Resuming: %d folders and %d files already finished\n", len(o.finishedDirs), len(o.finishedFiles))
+		}
+	} else {
+		flags |= os.O_TRUNC
+	}
+
+	// The checkpoint is bookkeeping, not a change to undo: its folders are not journaled unless
+	// files are put in them, and a completed run removes them again.
+	created := missingDirs(filepath.Dir(checkpointPath), dirMissing)
+	if err := os.MkdirAll(filepath.Dir(checkpointPath), 0755); err != nil {
+		log.Printf("Error creating checkpoint, the run cannot be resumed: %v", err)
+		return
+	}
+	f, err := os.OpenFile(checkpointPath, flags, 0644)
+	if err != nil {
+		log.Printf("Error creating checkpoint, the run cannot be resumed: %v", err)
+		return
+	}
+	o.checkpointMu.Lock()
+	o.checkpoint = f
+	o.checkpointDirs = make(map[string]bool)
+	for _, d := range created {
+		o.checkpointDirs[d] = true
+	}
+	o.checkpointMu.Unlock()
+	o.recoverStarted(started)
+}
+
+// loadCheckpoint reads the finished folders and files of an interrupted run and returns the
+// files it had started on but not finished.
+func (o *Organizer) loadCheckpoint(checkpointPath string) (map[string]checkpointEntry, error) {
+	f, err := os.Open(checkpointPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	o.finishedDirs = make(map[string]bool)
+	o.finishedFiles = make(map[string]bool)
+	started := make(map[string]checkpointEntry)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry checkpointEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// The line the run was killed in the middle of.
+			continue
+		}
+		switch entry.Op {
+		case checkpointStart:
+			started[entry.Path] = entry
+		case checkpointDone:
+			delete(started, entry.Path)
+			o.finishedFiles[entry.Path] = true
+			if entry.Day != "" {
+				// Finished files keep their place in the per-day numbering of renamed files.
+				t, err := time.Parse(time.RFC3339Nano, entry.Time)
+				if err == nil {
+					o.pending = append(o.pending, pendingRename{path: entry.Path, day: entry.Day, time: t})
+				}
+			}
+		case checkpointDir:
+			o.finishedDirs[entry.Path] = true
+		}
+	}
+	return started, scanner.Err()
+}
+
+// recoverStarted cleans up after the files the interrupted run was copying or moving. When the
+// original is still there and the run had created the destination, the destination may be
+// incomplete; it is removed together with any temporary copy, and the file is organized again.
+// A destination that existed before is left alone: unchanged, the file is organized again;
+// changed, it may be half overwritten and the file is skipped for a person to check. When only
+// the destination is left, the file had arrived.
+func (o *Organizer) recoverStarted(started map[string]checkpointEntry) {
+	for src, entry := range started {
+		dst := entry.Destination
+		partials, _ := filepath.Glob(filepath.Join(filepath.Dir(dst), "."+filepath.Base(dst)+".*.partial"))
+		for _, partial := range partials {
+			if err := os.Remove(partial); err != nil {
+				log.Printf("Error removing incomplete copy: %v", err)
+			}
+		}
+
+		_, srcErr := os.Lstat(src)
+		dstStat, dstErr := os.Lstat(dst)
+		switch {
+		case srcErr == nil && dstErr == nil && !entry.Existed:
+			if o.VerboseMode == "1" {
+				fmt.Printf("Removing incomplete copy %s of %s\n", dst, src)
+			}
+			if err := os.Remove(dst); err != nil {
+				log.Printf("Error removing incomplete copy: %v", err)
+			}
+		case srcErr == nil && dstErr == nil:
+			if dstStat.Size() != entry.Size || dstStat.ModTime().UnixNano() != entry.ModTime {
+				log.Printf("Error resuming %s: %s existed before and changed, it may be incomplete; leaving both for you to check", src, dst)
+				o.finishedFiles[src] = true
+			}
+		case srcErr != nil && dstErr == nil:
+			o.finishedFiles[src] = true
+			o.writeCheckpoint(checkpointEntry{Op: checkpointDone, Path: src})
+		}
+	}
+}
+
+// checkpointStarted records a file about to be copied or moved, and what is at its destination.
+func (o *Organizer) checkpointStarted(fileEntry FileData) {
+	o.checkpointMu.Lock()
+	active := o.checkpoint != nil
+	o.checkpointMu.Unlock()
+	if !active {
+		return
+	}
+	entry := checkpointEntry{Op: checkpointStart, Path: fileEntry.Path, Destination: fileEntry.NewPath}
+	if stat, err := os.Lstat(fileEntry.NewPath); err == nil {
+		entry.Existed, entry.Size, entry.ModTime = true, stat.Size(), stat.ModTime().UnixNano()
+	}
+	o.writeCheckpoint(entry)
+}
+
+// writeCheckpoint appends an entry to the checkpoint, straight to the file so it survives the
+// run being killed.
+func (o *Organizer) writeCheckpoint(entry checkpointEntry) {
+	o.checkpointMu.Lock()
+	defer o.checkpointMu.Unlock()
+	if o.checkpoint == nil {
+		return
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("Error writing checkpoint: %v", err)
+		return
+	}
+	if _, err := o.checkpoint.Write(append(line, '\n')); err != nil {
+		log.Printf("Error writing checkpoint: %v", err)
+	}
+}
+
+// checkpointDone records a finished file, with its place in the numbering of renamed files.
+func (o *Organizer) checkpointDone(filePath string) {
+	entry := checkpointEntry{Op: checkpointDone, Path: filePath}
+	if o.renameTemplate() != nil {
+		if p, ok := o.numbered(filePath); ok {
+			entry.Day, entry.Time = p.day, p.time.Format(time.RFC3339Nano)
+		}
+	}
+	o.writeCheckpoint(entry)
+}
+
+// checkpointCreated reports whether a folder was created for the checkpoint and is not
+// journaled yet.
+func (o *Organizer) checkpointCreated(dir string) bool {
+	o.checkpointMu.Lock()
+	defer o.checkpointMu.Unlock()
+	return o.checkpointDirs[dir]
+}
+
+// finished reports whether a resumed run already organized a folder or file.
+func (o *Organizer) finished(filePath string) bool {
+	return o.finishedDirs[filePath] || o.finishedFiles[filePath]
+}
+
+// closeCheckpoint closes the checkpoint; a completed run removes it, as there is nothing left to
+// resume.
+func (o *Organizer) closeCheckpoint(completed bool) {
+	o.checkpointMu.Lock()
+	defer o.checkpointMu.Unlock()
+	if o.checkpoint == nil {
+		return
+	}
+	checkpointPath := o.checkpoint.Name()
+	if err := o.checkpoint.Close(); err != nil {
+		log.Printf("Error closing checkpoint: %v", err)
+	}
+	o.checkpoint = nil
+	created := o.checkpointDirs
+	o.checkpointDirs = nil
+	if completed {
+		if err := os.Remove(checkpointPath); err != nil {
+			log.Printf("Error removing checkpoint: %v", err)
+		}
+		// Unless it holds journals, the folder goes too, and so do the folders created for it
+		// that nothing was put in.
+		os.Remove(filepath.Dir(checkpointPath))
+		dirs := make([]string, 0, len(created))
+		for d := range created {
+			dirs = append(dirs, d)
+		}
+		sort.Slice(dirs, func(i, j int) bool { return len(dirs[i]) > len(dirs[j]) })
+		for _, d := range dirs {
+			os.Remove(d)
+		}
+	}
+}
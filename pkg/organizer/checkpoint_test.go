@@ -0,0 +1,181 @@
+package organizer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestCheckpoint writes the checkpoint an interrupted run would have left behind.
+func writeTestCheckpoint(t *testing.T, org *Organizer, entries ...checkpointEntry) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(org.checkpointPath()), 0755); err != nil {
+		t.Fatal(err)
+	}
+	var data []byte
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = append(append(data, line...), '\n')
+	}
+	// The run was killed while writing the last line.
+	data = append(data, `{"op":"do`...)
+	if err := os.WriteFile(org.checkpointPath(), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResume(t *testing.T) {
+	src, library := t.TempDir(), t.TempDir()
+	for _, dir := range []string{"2022", "2023"} {
+		if err := os.MkdirAll(filepath.Join(src, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeTestFiles(t, filepath.Join(src, "2022"), "IMG_20220101_100000.xyz")
+	writeTestFiles(t, filepath.Join(src, "2023"), "IMG_20230501_143000.xyz", "IMG_20230502_100000.xyz", "IMG_20230503_100000.xyz")
+
+	org := NewOrganizer(src, "ymd", "generated", "seq", "0", "copy")
+	org.Extractors = testChainRegistry()
+	org.OutputRoot = library
+	org.Resume = true
+
+	// The interrupted run finished 2022 and the first file of 2023, and was killed while copying
+	// the second one.
+	halfCopied := filepath.Join(library, "20230502", "IMG_20230502_100000.xyz")
+	if err := os.MkdirAll(filepath.Dir(halfCopied), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(halfCopied, []byte("cus"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	partial := filepath.Join(library, "20230502", ".IMG_20230502_100000.xyz.123.partial")
+	if err := os.WriteFile(partial, []byte("cu"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeTestCheckpoint(t, org,
+		checkpointEntry{Op: checkpointStart, Path: filepath.Join(src, "2022", "IMG_20220101_100000.xyz")},
+		checkpointEntry{Op: checkpointDone, Path: filepath.Join(src, "2022", "IMG_20220101_100000.xyz")},
+		checkpointEntry{Op: checkpointDir, Path: filepath.Join(src, "2022")},
+		checkpointEntry{Op: checkpointDone, Path: filepath.Join(src, "2023", "IMG_20230501_143000.xyz")},
+		checkpointEntry{Op: checkpointStart, Path: filepath.Join(src, "2023", "IMG_20230502_100000.xyz"), Destination: halfCopied},
+	)
+	org.Run(0)
+
+	// Finished work is not done again.
+	for _, name := range []string{"20220101", "20230501"} {
+		if _, err := os.Stat(filepath.Join(library, name)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be skipped, got %v", name, err)
+		}
+	}
+	for _, name := range []string{"20230502/IMG_20230502_100000.xyz", "20230503/IMG_20230503_100000.xyz"} {
+		if got, err := os.ReadFile(filepath.Join(library, filepath.FromSlash(name))); string(got) != "custom" {
+			t.Errorf("Expected %s to be copied in full, got %q (%v)", name, got, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(library, "20230502", "IMG_20230502_100000_1.xyz")); !os.IsNotExist(err) {
+		t.Errorf("Expected the half-copied file to be replaced, not renamed: %v", err)
+	}
+	if _, err := os.Stat(partial); !os.IsNotExist(err) {
+		t.Errorf("Expected the temporary copy to be removed, got %v", err)
+	}
+	if _, err := os.Stat(org.checkpointPath()); !os.IsNotExist(err) {
+		t.Errorf("Expected the checkpoint of a completed run to be removed, got %v", err)
+	}
+}
+
+func TestCheckpointRecordsProgress(t *testing.T) {
+	src, library := t.TempDir(), t.TempDir()
+	writeTestFiles(t, src, "IMG_20230501_143000.xyz", "IMG_20230501_150000.xyz")
+
+	org := NewOrganizer(src, "ymd", "generated", "seq", "0", "move")
+	org.Extractors = testChainRegistry()
+	org.OutputRoot = library
+	org.RenameFormat = "{seq:03}"
+	org.startCheckpoint()
+	org.scanForDateFolders(src)
+	org.createFolders()
+	org.ProcessFilesInBatches(src, 0)
+	org.closeCheckpoint(false)
+
+	resumed := NewOrganizer(src, "ymd", "generated", "seq", "0", "move")
+	resumed.OutputRoot = library
+	started, err := resumed.loadCheckpoint(resumed.checkpointPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(started) != 0 || !resumed.finished(src) || len(resumed.finishedFiles) != 2 {
+		t.Errorf("Expected a finished source, got started %v, folders %v, files %v", started, resumed.finishedDirs, resumed.finishedFiles)
+	}
+	// Finished files keep their sequence numbers for the files still to do.
+	resumed.RenameFormat = "{seq:03}"
+	if got := resumed.sequence(filepath.Join(src, "IMG_20230501_150000.xyz")); got != 2 {
+		t.Errorf("Expected the second file of the day to stay number 2, got %d", got)
+	}
+}
+
+func TestResumeKeepsExistingDestination(t *testing.T) {
+	src, library := t.TempDir(), t.TempDir()
+	writeTestFiles(t, src, "IMG_20230501_143000.xyz", "IMG_20230502_100000.xyz")
+	for _, day := range []string{"20230501", "20230502"} {
+		if err := os.MkdirAll(filepath.Join(library, day), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Both destinations were library files the interrupted run was about to overwrite; the
+	// second one was half overwritten when it was killed.
+	untouched := filepath.Join(library, "20230501", "IMG_20230501_143000.xyz")
+	halfOverwritten := filepath.Join(library, "20230502", "IMG_20230502_100000.xyz")
+	var entries []checkpointEntry
+	for _, dst := range []string{untouched, halfOverwritten} {
+		if err := os.WriteFile(dst, []byte("library"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		stat, err := os.Stat(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, checkpointEntry{Op: checkpointStart, Path: filepath.Join(src, filepath.Base(dst)), Destination: dst,
+			Existed: true, Size: stat.Size(), ModTime: stat.ModTime().UnixNano()})
+	}
+	if err := os.WriteFile(halfOverwritten, []byte("cus"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	org := NewOrganizer(src, "ymd", "generated", "seq", "0", "copy")
+	org.Extractors = testChainRegistry()
+	org.OutputRoot = library
+	org.Resume = true
+	writeTestCheckpoint(t, org, entries...)
+	org.Run(0)
+
+	if got, _ := os.ReadFile(untouched); string(got) != "library" {
+		t.Errorf("Expected the untouched library file to stay, got %q", got)
+	}
+	if got, _ := os.ReadFile(filepath.Join(library, "20230501", "IMG_20230501_143000_1.xyz")); string(got) != "custom" {
+		t.Errorf("Expected the file to be organized again next to it, got %q", got)
+	}
+	if got, _ := os.ReadFile(halfOverwritten); string(got) != "cus" {
+		t.Errorf("Expected the changed library file to be left for checking, got %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(library, "20230502", "IMG_20230502_100000_1.xyz")); !os.IsNotExist(err) {
+		t.Errorf("Expected the file with a changed destination to be skipped, got %v", err)
+	}
+}
+
+func TestCheckpointIsNotJournaled(t *testing.T) {
+	src := t.TempDir()
+	writeTestFiles(t, src, "notes.txt")
+
+	org := NewOrganizer(src, "ymd", "generated", "seq", "0", "move")
+	org.Extractors = testChainRegistry()
+	org.Run(0)
+
+	// Nothing changed, so there is no journal, and the checkpoint left nothing behind.
+	if _, err := os.Stat(filepath.Join(src, "generated")); !os.IsNotExist(err) {
+		t.Errorf("Expected no library folder after a run that changed nothing, got %v", err)
+	}
+}
@@ -0,0 +1,351 @@
+package organizer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Per-camera clock corrections ---
+
+// ClockOffset is a calendar offset such as "+1y2d3h" added to a camera's clock.
+type ClockOffset struct {
+	Years, Months, Days int
+	Duration            time.Duration
+}
+
+// ClockRule corrects the capture times of the files taken by one camera or found under one folder.
+// Every selector that is set must match. The correction is either Offset or, when RefFile is set,
+// the difference between the capture time of that reference shot and RefTime, the moment it was
+// really taken.
+type ClockRule struct {
+	Make, Model, Serial string
+	Dir                 string
+
+	Offset  ClockOffset
+	RefFile string
+	RefTime time.Time
+
+	Line     string // the rule as written in the clock table
+	resolved bool
+}
+
+// ClockCorrection records a capture time moved by a clock rule.
+type ClockCorrection struct {
+	Rule      string
+	Original  time.Time
+	Corrected time.Time
+}
+
+// CorrectedFile is a file whose capture time a clock rule corrected.
+type CorrectedFile struct {
+	Path string
+	ClockCorrection
+}
+
+// clockOffsetPattern matches one component of a clock offset.
+var clockOffsetPattern = regexp.MustCompile(`(\d+)(mo|y|w|d|h|m|s)`)
+
+// clockTimeLayouts lists the formats accepted for reference times.
+var clockTimeLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006:01:02 15:04:05",
+	"2006-01-02T15:04",
+}
+
+// ParseClockOffset parses an offset such as "+1y2d3h" or "-45m". Units are y (years), mo (months),
+// w (weeks), d (days), h (hours), m (minutes) and s (seconds).
+func ParseClockOffset(s string) (ClockOffset, error) {
+	s = strings.TrimSpace(s)
+	sign, body := 1, s
+	switch {
+	case strings.HasPrefix(s, "-"):
+		sign, body = -1, s[1:]
+	case strings.HasPrefix(s, "+"):
+		body = s[1:]
+	}
+
+	var offset ClockOffset
+	matches := clockOffsetPattern.FindAllStringSubmatch(body, -1)
+	if body == "" || clockOffsetPattern.ReplaceAllString(body, "") != "" {
+		return offset, fmt.Errorf("invalid clock offset %q", s)
+	}
+	for _, m := range matches {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return offset, fmt.Errorf("invalid clock offset %q", s)
+		}
+		n *= sign
+		switch m[2] {
+		case "y":
+			offset.Years += n
+		case "mo":
+			offset.Months += n
+		case "w":
+			offset.Days += 7 * n
+		case "d":
+			offset.Days += n
+		case "h":
+			offset.Duration += time.Duration(n) * time.Hour
+		case "m":
+			offset.Duration += time.Duration(n) * time.Minute
+		case "s":
+			offset.Duration += time.Duration(n) * time.Second
+		}
+	}
+	return offset, nil
+}
+
+// Apply adds the offset to t.
+func (c ClockOffset) Apply(t time.Time) time.Time {
+	return t.AddDate(c.Years, c.Months, c.Days).Add(c.Duration)
+}
+
+// ParseClockTable reads a clock correction table. Each line holds key=value fields: the selectors
+// make, model, serial and dir, and either offset=+1y2d3h or reference=FILE@TIME, meaning the
+// reference shot FILE was really taken at TIME. Values with spaces are double-quoted; blank lines
+// and lines starting with # are ignored.
+//
+//	make=Canon model="Canon EOS 5D" offset=+8y4mo2d3h
+//	dir=/photos/card2 reference=IMG_0001.JPG@2023-05-01T14:30:00
+func ParseClockTable(r io.Reader) ([]*ClockRule, error) {
+	var rules []*ClockRule
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := parseClockRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// LoadClockTable reads a clock correction table from a file.
+func LoadClockTable(path string) ([]*ClockRule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseClockTable(f)
+}
+
+// parseClockRule parses one line of a clock correction table.
+func parseClockRule(line string) (*ClockRule, error) {
+	fields, err := splitClockFields(line)
+	if err != nil {
+		return nil, err
+	}
+
+	rule := &ClockRule{Line: line}
+	var hasCorrection bool
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("field %q is not key=value", field)
+		}
+		switch key {
+		case "make":
+			rule.Make = value
+		case "model":
+			rule.Model = value
+		case "serial":
+			rule.Serial = value
+		case "dir":
+			rule.Dir = filepath.Clean(value)
+		case "offset":
+			if rule.Offset, err = ParseClockOffset(value); err != nil {
+				return nil, err
+			}
+			hasCorrection = true
+		case "reference":
+			file, at, ok := strings.Cut(value, "@")
+			if !ok {
+				return nil, fmt.Errorf("reference %q is not FILE@TIME", value)
+			}
+			if rule.RefTime, ok = parseClockTime(at); !ok {
+				return nil, fmt.Errorf("invalid reference time %q", at)
+			}
+			rule.RefFile = file
+			hasCorrection = true
+		default:
+			return nil, fmt.Errorf("unknown field %q", key)
+		}
+	}
+
+	if rule.Make == "" && rule.Model == "" && rule.Serial == "" && rule.Dir == "" {
+		return nil, fmt.Errorf("rule %q selects no camera or folder", line)
+	}
+	if !hasCorrection {
+		return nil, fmt.Errorf("rule %q has no offset or reference", line)
+	}
+	return rule, nil
+}
+
+// splitClockFields splits a line at spaces outside double quotes and removes the quotes.
+func splitClockFields(line string) ([]string, error) {
+	var fields []string
+	var field strings.Builder
+	inQuotes, inField := false, false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			inQuotes, inField = !inQuotes, true
+		case (r == ' ' || r == '\t') && !inQuotes:
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		default:
+			field.WriteRune(r)
+			inField = true
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated quote in %q", line)
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+	return fields, nil
+}
+
+// parseClockTime parses a reference time as a wall clock.
+func parseClockTime(s string) (time.Time, bool) {
+	for _, layout := range clockTimeLayouts {
+		if t, err := time.Parse(layout, strings.TrimSpace(s)); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// wallClock returns the wall clock reading of t as a UTC time.
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// matches reports whether the rule applies to a file with the given metadata.
+func (r *ClockRule) matches(filePath string, info *MediaInfo) bool {
+	if r.Make != "" && !strings.EqualFold(r.Make, info.Make) {
+		return false
+	}
+	if r.Model != "" && !strings.EqualFold(r.Model, info.Model) {
+		return false
+	}
+	if r.Serial != "" && r.Serial != info.Serial {
+		return false
+	}
+	if r.Dir != "" {
+		// A relative folder in the table is relative to the working directory, like the sources.
+		dir, err := filepath.Abs(r.Dir)
+		if err != nil {
+			return false
+		}
+		fileDir, err := filepath.Abs(filepath.Dir(filePath))
+		if err != nil {
+			return false
+		}
+		rel, err := filepath.Rel(dir, fileDir)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveReference turns a reference shot into an offset by reading the shot's capture time.
+func (o *Organizer) resolveReference(r *ClockRule) error {
+	if r.resolved || r.RefFile == "" {
+		return nil
+	}
+	r.resolved = true
+
+	refPath := r.RefFile
+	if !filepath.IsAbs(refPath) {
+		base := r.Dir
+		if base == "" {
+			base = o.SrcPath
+		}
+		refPath = filepath.Join(base, refPath)
+	}
+	info, err := o.readMediaInfo(refPath)
+	if err != nil {
+		r.RefFile = ""
+		return fmt.Errorf("reference shot of clock rule %q: %w", r.Line, err)
+	}
+	r.Offset = ClockOffset{Duration: wallClock(r.RefTime).Sub(wallClock(info.CaptureTime))}
+	return nil
+}
+
+// cameraClock reports whether a date source is a reading of the camera clock: the EXIF, XMP and
+// QuickTime dates and those of custom extractors. GPS time, PNG creation times written by
+// software, Takeout dates, dates in names and file times are not.
+func cameraClock(source DateSource) bool {
+	switch source {
+	case DateSourceExifGPS, DateSourcePNGCreationTime, DateSourceTakeoutPhotoTaken, DateSourceTakeoutCreation,
+		DateSourceFilename, DateSourceFolder, DateSourceModTime:
+		return false
+	}
+	return true
+}
+
+// correctClock applies the first matching clock rule to the capture time chosen for a file.
+// Times that do not come from the camera clock are left alone.
+func (o *Organizer) correctClock(filePath string, info *MediaInfo) {
+	if !cameraClock(info.DateSource) {
+		return
+	}
+	for _, rule := range o.ClockRules {
+		if !rule.matches(filePath, info) {
+			continue
+		}
+		if err := o.resolveReference(rule); err != nil {
+			log.Printf("Error resolving clock rule: %v", err)
+		}
+		if rule.Offset == (ClockOffset{}) {
+			return
+		}
+		corrected := rule.Offset.Apply(info.CaptureTime)
+		info.Correction = &ClockCorrection{Rule: rule.Line, Original: info.CaptureTime, Corrected: corrected}
+		info.CaptureTime = corrected
+		return
+	}
+}
+
+// recordCorrection remembers a corrected file for the end-of-run report.
+func (o *Organizer) recordCorrection(filePath string, info *MediaInfo) {
+	if info.Correction != nil {
+		o.corrections = append(o.corrections, CorrectedFile{Path: filePath, ClockCorrection: *info.Correction})
+	}
+}
+
+// Corrections returns the files whose capture time a clock rule corrected in the current run.
+func (o *Organizer) Corrections() []CorrectedFile {
+	return o.corrections
+}
+
+// printCorrections reports every file whose capture time a clock rule corrected.
+func (o *Organizer) printCorrections() {
+	if len(o.corrections) == 0 {
+		return
+	}
+	fmt.Printf("Corrected camera clock of %d files:\n", len(o.corrections))
+	for _, c := range o.corrections {
+		fmt.Printf("  %s: %s -> %s (%s)\n", c.Path, c.Original.Format("2006-01-02 15:04:05"), c.Corrected.Format("2006-01-02 15:04:05"), c.Rule)
+	}
+}
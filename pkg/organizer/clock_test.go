@@ -0,0 +1,143 @@
+package organizer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseClockOffset(t *testing.T) {
+	tests := []struct {
+		s    string
+		want ClockOffset
+	}{
+		{"+1y2d3h", ClockOffset{Years: 1, Days: 2, Duration: 3 * time.Hour}},
+		{"8y4mo1w", ClockOffset{Years: 8, Months: 4, Days: 7}},
+		{"-45m30s", ClockOffset{Duration: -45*time.Minute - 30*time.Second}},
+	}
+	for _, tt := range tests {
+		got, err := ParseClockOffset(tt.s)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseClockOffset(%q) = %+v, %v; want %+v", tt.s, got, err, tt.want)
+		}
+	}
+	for _, s := range []string{"", "+", "3x", "1y 2d", "++1d"} {
+		if _, err := ParseClockOffset(s); err == nil {
+			t.Errorf("Expected error for offset %q", s)
+		}
+	}
+}
+
+func TestParseClockTable(t *testing.T) {
+	rules, err := ParseClockTable(strings.NewReader(`
+# Cameras that were never set
+make=Canon model="Canon EOS 5D" serial=1234 offset=+8y4mo2d3h
+dir=/photos/card2	reference=IMG_0001.JPG@2023-05-01T14:30:00
+`))
+	if err != nil {
+		t.Fatalf("ParseClockTable returned error: %v", err)
+	}
+	if len(rules) != 2 {
+		t.Fatalf("Expected 2 rules, got %d", len(rules))
+	}
+	if rules[0].Model != "Canon EOS 5D" || rules[0].Serial != "1234" || rules[0].Offset.Years != 8 {
+		t.Errorf("Unexpected first rule %+v", rules[0])
+	}
+	if rules[1].Dir != "/photos/card2" || rules[1].RefFile != "IMG_0001.JPG" || !rules[1].RefTime.Equal(time.Date(2023, 5, 1, 14, 30, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected second rule %+v", rules[1])
+	}
+
+	for _, table := range []string{"offset=+1d", "make=Canon", "make=Canon offset=+1d lens=50mm", `model="EOS offset=+1d`, "dir=/x reference=IMG_0001.JPG"} {
+		if _, err := ParseClockTable(strings.NewReader(table)); err == nil {
+			t.Errorf("Expected error for table %q", table)
+		}
+	}
+}
+
+func TestCorrectClock(t *testing.T) {
+	dir := t.TempDir()
+	card := filepath.Join(dir, "card2")
+	if err := os.Mkdir(card, 0755); err != nil {
+		t.Fatalf("Failed to create folder: %v", err)
+	}
+	writeTestFiles(t, card, "IMG_0001.xyz", "IMG_0002.xyz")
+	writeTestFiles(t, dir, "IMG_0003.xyz")
+
+	// The camera clock is stuck in 2015; the reference shot was really taken at 2023-05-01 14:30.
+	cameraTime := time.Date(2015, 1, 1, 0, 10, 0, 0, time.UTC)
+	rules, err := ParseClockTable(strings.NewReader(`dir=` + card + ` reference=IMG_0001.xyz@2023-05-01T14:30:00
+make=Canon offset=+1d`))
+	if err != nil {
+		t.Fatalf("ParseClockTable returned error: %v", err)
+	}
+
+	org := NewOrganizer(dir, "ymd", "generated", "seq", "0", "copy")
+	org.Extractors = testChainRegistry(DateCandidate{Time: cameraTime, Source: DateSourceExifOriginal})
+	org.ClockRules = rules
+
+	info, err := org.resolveDate(filepath.Join(card, "IMG_0002.xyz"))
+	if err != nil {
+		t.Fatalf("resolveDate returned error: %v", err)
+	}
+	if want := time.Date(2023, 5, 1, 14, 30, 0, 0, time.UTC); !info.CaptureTime.Equal(want) {
+		t.Errorf("Expected corrected time %s, got %s", want, info.CaptureTime)
+	}
+	if info.Correction == nil || !info.Correction.Original.Equal(cameraTime) || info.Correction.Rule != rules[0].Line {
+		t.Errorf("Expected the correction to be recorded, got %+v", info.Correction)
+	}
+
+	// Make and model come from the metadata; without a match nothing changes.
+	info, err = org.resolveDate(filepath.Join(dir, "IMG_0003.xyz"))
+	if err != nil {
+		t.Fatalf("resolveDate returned error: %v", err)
+	}
+	if !info.CaptureTime.Equal(cameraTime) || info.Correction != nil {
+		t.Errorf("Expected no correction, got %s (%+v)", info.CaptureTime, info.Correction)
+	}
+
+	org.AddFileEntries(dir)
+	if got := len(org.Corrections()); got != 2 {
+		t.Errorf("Expected 2 corrected files in the report, got %d", got)
+	}
+}
+
+func TestCorrectClockRelativeDir(t *testing.T) {
+	card := t.TempDir()
+	writeTestFiles(t, card, "IMG_0001.xyz", "IMG_20230501_143000.xyz")
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	relCard, err := filepath.Rel(wd, card)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, err := ParseClockTable(strings.NewReader(`dir=` + relCard + ` offset=+1d`))
+	if err != nil {
+		t.Fatalf("ParseClockTable returned error: %v", err)
+	}
+
+	cameraTime := time.Date(2023, 5, 1, 14, 30, 0, 0, time.UTC)
+	org := NewOrganizer(card, "ymd", "generated", "seq", "0", "copy")
+	org.Extractors = testChainRegistry(DateCandidate{Time: cameraTime, Source: DateSourceExifOriginal})
+	org.ClockRules = rules
+	info, err := org.resolveDate(filepath.Join(card, "IMG_0001.xyz"))
+	if err != nil {
+		t.Fatalf("resolveDate returned error: %v", err)
+	}
+	if want := cameraTime.AddDate(0, 0, 1); !info.CaptureTime.Equal(want) {
+		t.Errorf("Expected the relative folder to match, got %s, want %s", info.CaptureTime, want)
+	}
+
+	// A date from the file name is not a camera clock reading.
+	org.Extractors = testChainRegistry()
+	info, err = org.resolveDate(filepath.Join(card, "IMG_20230501_143000.xyz"))
+	if err != nil {
+		t.Fatalf("resolveDate returned error: %v", err)
+	}
+	if info.DateSource != DateSourceFilename || !info.CaptureTime.Equal(cameraTime) || info.Correction != nil {
+		t.Errorf("Expected the file name date to stay, got %s from %s (%+v)", info.CaptureTime, info.DateSource, info.Correction)
+	}
+}
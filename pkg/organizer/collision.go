@@ -0,0 +1,256 @@
+package organizer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// --- Destination collisions ---
+
+// Collision policies decide what happens when a file's destination already exists.
+const (
+	CollisionSkip          = "skip"           // leave the file where it is
+	CollisionOverwrite     = "overwrite"      // replace the existing file
+	CollisionRename        = "rename"         // add a _1, _2, ... suffix (default)
+	CollisionKeepNewer     = "keep-newer"     // replace the existing file if the new one was modified later
+	CollisionSkipIdentical = "skip-identical" // leave files with the same content, rename the others
+)
+
+// Collision records how a taken destination was resolved.
+type Collision struct {
+	Path    string // file being organized
+	Target  string // destination that was taken
+	NewPath string // where the file went; empty when it was skipped
+	Action  string // CollisionSkip, CollisionOverwrite or CollisionRename
+	Reason  string
+}
+
+// ParseCollisionPolicy checks a collision policy; "" is CollisionRename.
+func ParseCollisionPolicy(s string) (string, error) {
+	switch s {
+	case "":
+		return CollisionRename, nil
+	case CollisionSkip, CollisionOverwrite, CollisionRename, CollisionKeepNewer, CollisionSkipIdentical:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown collision policy %q (skip/overwrite/rename/keep-newer/skip-identical)", s)
+}
+
+// resolveCollision picks the destination of a file and claims it for this run, so two files of
+// the run never write to the same path. The returned collision's NewPath is the destination; its
+// Action is empty when the destination was free. It returns false when the file is to stay where
+// it is. Whoever claims a destination settles the claim with settleClaim once the file arrived
+// or was given up.
+func (o *Organizer) resolveCollision(fileEntry FileData) (Collision, bool) {
+	o.collisionMu.Lock()
+	defer o.collisionMu.Unlock()
+	if o.claimed == nil {
+		o.claimed = make(map[string]string)
+		o.arriving = make(map[string]chan struct{})
+	}
+
+	target := fileEntry.NewPath
+	var existing, policy string
+	for {
+		var taken bool
+		existing, taken = o.takenBy(target)
+		if !taken {
+			o.claim(target, fileEntry.Path)
+			return Collision{Path: fileEntry.Path, Target: target, NewPath: target}, true
+		}
+
+		var err error
+		policy, err = ParseCollisionPolicy(o.CollisionPolicy)
+		if err != nil {
+			log.Printf("Error resolving collision, renaming: %v", err)
+			policy = CollisionRename
+		}
+		arrived, pending := o.arriving[target]
+		if !pending || (policy != CollisionOverwrite && policy != CollisionKeepNewer) {
+			break
+		}
+		// Replacing a file that has not arrived yet would race with it; wait until it is there or
+		// was given up, then decide.
+		o.collisionMu.Unlock()
+		<-arrived
+		o.collisionMu.Lock()
+	}
+
+	collision := Collision{Path: fileEntry.Path, Target: target, Action: policy}
+	switch policy {
+	case CollisionKeepNewer:
+		newer, err := isNewer(fileEntry.Path, existing)
+		switch {
+		case err != nil:
+			log.Printf("Error comparing files: %v", err)
+			collision.Action, collision.Reason = CollisionSkip, err.Error()
+		case newer:
+			collision.Action, collision.Reason = CollisionOverwrite, "file is newer"
+		default:
+			collision.Action, collision.Reason = CollisionSkip, "destination is newer"
+		}
+	case CollisionSkipIdentical:
+		same, err := sameContent(fileEntry.Path, existing)
+		switch {
+		case err != nil:
+			log.Printf("Error comparing files: %v", err)
+			collision.Action, collision.Reason = CollisionRename, err.Error()
+		case same:
+			collision.Action, collision.Reason = CollisionSkip, "identical content"
+		default:
+			collision.Action, collision.Reason = CollisionRename, "different content"
+		}
+	}
+
+	switch collision.Action {
+	case CollisionRename:
+		collision.NewPath = o.freePath(target)
+	case CollisionOverwrite:
+		collision.NewPath = target
+	}
+	if collision.NewPath != "" {
+		o.claim(collision.NewPath, fileEntry.Path)
+	}
+	o.collisions = append(o.collisions, collision)
+	return collision, collision.NewPath != ""
+}
+
+// claim reserves a destination for a file of the run until the file arrives. The caller holds
+// collisionMu.
+func (o *Organizer) claim(target, filePath string) {
+	o.claimed[target] = filePath
+	o.arriving[target] = make(chan struct{})
+}
+
+// settleClaim records that the file that claimed its destination arrived there, or was given up,
+// which frees the destination again.
+func (o *Organizer) settleClaim(fileEntry FileData, arrived bool) {
+	o.collisionMu.Lock()
+	defer o.collisionMu.Unlock()
+	done, ok := o.arriving[fileEntry.NewPath]
+	if !ok || o.claimed[fileEntry.NewPath] != fileEntry.Path {
+		return
+	}
+	delete(o.arriving, fileEntry.NewPath)
+	if !arrived {
+		delete(o.claimed, fileEntry.NewPath)
+	}
+	close(done)
+}
+
+// decision describes how a collision was resolved, or returns "" when there was none.
+func (c Collision) decision() string {
+	if c.Action == "" {
+		return ""
+	}
+	if c.Reason == "" {
+		return "collision: " + c.Action
+	}
+	return "collision: " + c.Action + " (" + c.Reason + ")"
+}
+
+// takenBy reports whether a destination exists or is claimed by another file of the run, and
+// which file holds its content.
+func (o *Organizer) takenBy(target string) (string, bool) {
+	if _, err := os.Lstat(target); err == nil {
+		return target, true
+	}
+	if source, ok := o.claimed[target]; ok {
+		// The claiming file has not arrived yet; compare against it where it still is.
+		return source, true
+	}
+	return "", false
+}
+
+// freePath returns the first of "name_1.ext", "name_2.ext", ... that is neither on disk nor claimed.
+func (o *Organizer) freePath(target string) string {
+	ext := filepath.Ext(target)
+	stem := strings.TrimSuffix(target, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s_%d%s", stem, i, ext)
+		if _, taken := o.takenBy(candidate); !taken {
+			return candidate
+		}
+	}
+}
+
+// isNewer reports whether file a was modified after file b.
+func isNewer(a, b string) (bool, error) {
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	infoB, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	return infoA.ModTime().After(infoB.ModTime()), nil
+}
+
+// sameContent reports whether two files have the same size and SHA-256 hash.
+func sameContent(a, b string) (bool, error) {
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	infoB, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	if infoA.Size() != infoB.Size() {
+		return false, nil
+	}
+	hashA, err := hashFile(a)
+	if err != nil {
+		return false, err
+	}
+	hashB, err := hashFile(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(hashA, hashB), nil
+}
+
+// hashFile returns the SHA-256 hash of a file's content.
+func hashFile(filePath string) ([]byte, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.CopyBuffer(h, f, make([]byte, 64*1024)); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// Collisions returns how the taken destinations of the current run were resolved.
+func (o *Organizer) Collisions() []Collision {
+	return o.collisions
+}
+
+// printCollisions reports every collision decision of the run.
+func (o *Organizer) printCollisions() {
+	if len(o.collisions) == 0 {
+		return
+	}
+	fmt.Printf("Resolved %d destination collisions:\n", len(o.collisions))
+	for _, c := range o.collisions {
+		line := fmt.Sprintf("  %s: %s %s", c.Path, c.Action, c.Target)
+		if c.NewPath != "" && c.NewPath != c.Target {
+			line += " as " + c.NewPath
+		}
+		if c.Reason != "" {
+			line += " (" + c.Reason + ")"
+		}
+		fmt.Println(line)
+	}
+}
@@ -0,0 +1,156 @@
+package organizer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCollisionPolicies(t *testing.T) {
+	const name = "IMG_20230501_143000.xyz"
+	tests := []struct {
+		policy   string
+		existing string
+		newer    bool   // the file being organized was modified after the existing one
+		want     string // content of the destination afterwards
+		wantAlt  string // content of name_1.xyz, "" when it must not exist
+	}{
+		{CollisionSkip, "old", true, "old", ""},
+		{CollisionOverwrite, "old", false, "new", ""},
+		{CollisionRename, "old", false, "old", "new"},
+		{"", "old", false, "old", "new"},
+		{CollisionKeepNewer, "old", true, "new", ""},
+		{CollisionKeepNewer, "old", false, "old", ""},
+		{CollisionSkipIdentical, "new", false, "new", ""},
+		{CollisionSkipIdentical, "old", false, "old", "new"},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		src := filepath.Join(dir, name)
+		dst := filepath.Join(dir, "generated", "20230501", name)
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(src, []byte("new"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(dst, []byte(tt.existing), 0644); err != nil {
+			t.Fatal(err)
+		}
+		old, recent := time.Now().Add(-time.Hour), time.Now()
+		if !tt.newer {
+			old, recent = recent, old
+		}
+		os.Chtimes(dst, old, old)
+		os.Chtimes(src, recent, recent)
+
+		org := NewOrganizer(dir, "ymd", "generated", "seq", "0", "move")
+		org.Extractors = testChainRegistry()
+		org.CollisionPolicy = tt.policy
+		org.AddFileEntries(dir)
+		org.OrganizeFiles(0)
+
+		if got, _ := os.ReadFile(dst); string(got) != tt.want {
+			t.Errorf("Policy %q with %q existing: destination holds %q, want %q", tt.policy, tt.existing, got, tt.want)
+		}
+		alt := filepath.Join(filepath.Dir(dst), "IMG_20230501_143000_1.xyz")
+		if got, err := os.ReadFile(alt); string(got) != tt.wantAlt || (err == nil) != (tt.wantAlt != "") {
+			t.Errorf("Policy %q with %q existing: renamed copy holds %q (%v), want %q", tt.policy, tt.existing, got, err, tt.wantAlt)
+		}
+		if len(org.Collisions()) != 1 {
+			t.Errorf("Policy %q: expected one collision in the summary, got %d", tt.policy, len(org.Collisions()))
+		}
+	}
+
+	if _, err := ParseCollisionPolicy("newest"); err == nil {
+		t.Error("Expected error for unknown collision policy")
+	}
+}
+
+func TestCollisionWithinRun(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"a", "b"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, sub, "IMG_20230501_143000.xyz"), []byte(sub), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	org := NewOrganizer(dir, "ymd", "generated", "con", "0", "copy")
+	org.Extractors = testChainRegistry()
+	org.AddFileEntries(dir)
+	org.OrganizeFiles(2)
+
+	seen := make(map[string]bool)
+	for _, name := range []string{"IMG_20230501_143000.xyz", "IMG_20230501_143000_1.xyz"} {
+		got, err := os.ReadFile(filepath.Join(dir, "generated", "20230501", name))
+		if err != nil {
+			t.Fatalf("Expected %s: %v", name, err)
+		}
+		seen[string(got)] = true
+	}
+	if !seen["a"] || !seen["b"] {
+		t.Errorf("Expected both files to survive, got %v", seen)
+	}
+
+	// With keep-newer, whichever of the two workers claims the destination first, the newer file
+	// ends up there: the older one is skipped, or replaced once it arrived. The older file is slow
+	// to arrive.
+	renameFile = func(src, dst string) error {
+		if filepath.Base(filepath.Dir(src)) == "a" {
+			time.Sleep(20 * time.Millisecond)
+		}
+		return os.Rename(src, dst)
+	}
+	t.Cleanup(func() { renameFile = os.Rename })
+	for run := 0; run < 10; run++ {
+		dir := t.TempDir()
+		for i, sub := range []string{"a", "b"} {
+			name := filepath.Join(dir, sub, "IMG_20230501_143000.xyz")
+			if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(name, []byte(sub), 0644); err != nil {
+				t.Fatal(err)
+			}
+			modTime := time.Date(2023, 5, 1+i, 0, 0, 0, 0, time.UTC)
+			if err := os.Chtimes(name, modTime, modTime); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// Moved files keep their modification time, so the arrived file compares as its source.
+		org := NewOrganizer(dir, "ymd", "generated", "con", "0", "move")
+		org.Extractors = testChainRegistry()
+		org.CollisionPolicy = CollisionKeepNewer
+		org.AddFileEntries(dir)
+		org.OrganizeFiles(2)
+
+		got, err := os.ReadFile(filepath.Join(dir, "generated", "20230501", "IMG_20230501_143000.xyz"))
+		if err != nil || string(got) != "b" {
+			t.Fatalf("Expected the newer file at the destination, got %q (%v)", got, err)
+		}
+		for _, summary := range org.SourceSummaries() {
+			if summary.Failed != 0 {
+				t.Fatalf("Expected no file to fail, got %+v", summary)
+			}
+		}
+		collisions := org.Collisions()
+		if len(collisions) != 1 {
+			t.Fatalf("Expected one collision, got %+v", collisions)
+		}
+		switch c := collisions[0]; filepath.Base(filepath.Dir(c.Path)) {
+		case "a":
+			if c.Action != CollisionSkip {
+				t.Fatalf("Expected the older file to be skipped, got %+v", c)
+			}
+		case "b":
+			if c.Action != CollisionOverwrite {
+				t.Fatalf("Expected the newer file to replace the older one, got %+v", c)
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package organizer
+
+import (
+	"bytes"
+	"io"
+)
+
+// --- Canon CR3 capture dates ---
+
+// canonCR3UUID identifies the moov/uuid box holding the CMT metadata boxes of a CR3 file.
+var canonCR3UUID = []byte{0x85, 0xc0, 0xb6, 0x87, 0x82, 0x0f, 0x11, 0xe0, 0x81, 0x11, 0xf4, 0xce, 0x46, 0x2b, 0x6a, 0x48}
+
+// extractCR3 reads capture metadata from the CMT boxes of a Canon CR3 file.
+// CMT1 holds IFD0, CMT2 the Exif IFD and CMT4 the GPS IFD, each as its own TIFF structure.
+func extractCR3(r io.ReadSeeker) (*MediaInfo, error) {
+	moov, err := findBMFFBox(r, "moov")
+	if err != nil {
+		return nil, ErrNoCaptureDate
+	}
+	children, err := bmffChildren(r, moov)
+	if err != nil && len(children) == 0 {
+		return nil, err
+	}
+
+	var canon *bmffBox
+	for i := range children {
+		if children[i].Type == "uuid" && bytes.Equal(children[i].UUID, canonCR3UUID) {
+			canon = &children[i]
+			break
+		}
+	}
+	if canon == nil {
+		return nil, ErrNoCaptureDate
+	}
+
+	boxes, err := bmffChildren(r, *canon)
+	if err != nil && len(boxes) == 0 {
+		return nil, err
+	}
+
+	ifds := make(map[string]tiffIFD)
+	for _, box := range boxes {
+		switch box.Type {
+		case "CMT1", "CMT2", "CMT4":
+			if ifd, err := readFirstIFD(r, box.dataOffset()); err == nil {
+				ifds[box.Type] = ifd
+			}
+		}
+	}
+	if len(ifds) == 0 {
+		return nil, ErrNoCaptureDate
+	}
+
+	return mediaInfoFromIFDs(ifds["CMT1"], ifds["CMT2"], ifds["CMT4"])
+}
@@ -0,0 +1,80 @@
+package organizer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCR3 builds a CR3 file whose Canon uuid box holds CMT1, CMT2 and CMT4 TIFF structures.
+func testCR3(withCMT2 bool) []byte {
+	order := binary.LittleEndian
+	cmt1 := testTIFF(order, []testTIFFTag{
+		testASCII(tiffTagMake, "Canon"),
+		testASCII(tiffTagModel, "Canon EOS R5"),
+		testASCII(tiffTagDateTime, "2023:06:10 08:00:00"),
+	}, nil, nil)
+	cmt2 := testTIFF(order, []testTIFFTag{
+		testASCII(tiffTagDateTimeOriginal, "2023:06:09 19:45:10"),
+	}, nil, nil)
+	cmt4 := testTIFF(order, []testTIFFTag{
+		testASCII(gpsTagLatitudeRef, "N"),
+		testRationals(order, gpsTagLatitude, 48, 1, 51, 1, 24, 1),
+		testASCII(gpsTagLongitudeRef, "E"),
+		testRationals(order, gpsTagLongitude, 2, 1, 21, 1, 3, 1),
+	}, nil, nil)
+
+	parts := [][]byte{canonCR3UUID, testBox("CNCV", []byte("CanonCR3_001/00.09.00/00.00.00")), testBox("CMT1", cmt1)}
+	if withCMT2 {
+		parts = append(parts, testBox("CMT2", cmt2))
+	}
+	parts = append(parts, testBox("CMT4", cmt4))
+
+	ftyp := testBox("ftyp", []byte("crx "), []byte{0, 0, 0, 1}, []byte("crx isom"))
+	moov := testBox("moov", testBox("uuid", parts...), testBox("mvhd", make([]byte, 100)))
+	return bytes.Join([][]byte{ftyp, moov, testBox("mdat", make([]byte, 32))}, nil)
+}
+
+func TestExtractCR3(t *testing.T) {
+	info, err := extractCR3(bytes.NewReader(testCR3(true)))
+	if err != nil {
+		t.Fatalf("extractCR3 returned error: %v", err)
+	}
+	want := time.Date(2023, 6, 9, 19, 45, 10, 0, time.UTC)
+	if !info.CaptureTime.Equal(want) || info.DateSource != DateSourceExifOriginal {
+		t.Errorf("Expected %s from %s, got %s from %s", want, DateSourceExifOriginal, info.CaptureTime, info.DateSource)
+	}
+	if info.Model != "Canon EOS R5" {
+		t.Errorf("Expected model from CMT1, got %q", info.Model)
+	}
+	if info.GPS == nil || info.GPS.Latitude < 48.8 || info.GPS.Longitude < 2.3 {
+		t.Errorf("Expected GPS from CMT4, got %+v", info.GPS)
+	}
+}
+
+func TestExtractCR3DateTimeFallback(t *testing.T) {
+	info, err := extractCR3(bytes.NewReader(testCR3(false)))
+	if err != nil {
+		t.Fatalf("extractCR3 returned error: %v", err)
+	}
+	if info.DateSource != DateSourceExifDateTime {
+		t.Errorf("Expected date source %s, got %s", DateSourceExifDateTime, info.DateSource)
+	}
+}
+
+func TestRegistryExtractCR3File(t *testing.T) {
+	data := testCR3(true)
+	filePath := filepath.Join(t.TempDir(), "IMG_0001.CR3")
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		t.Fatalf("Failed to write CR3 file: %v", err)
+	}
+	if _, err := DefaultRegistry.Extract(filePath); err != nil {
+		t.Errorf("Extract returned error: %v", err)
+	}
+	if got := sniffContentType(data[:32]); got != "image/x-canon-cr3" {
+		t.Errorf("Expected image/x-canon-cr3 from ftyp brand, got %s", got)
+	}
+}
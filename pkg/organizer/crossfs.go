@@ -0,0 +1,90 @@
+package organizer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// --- Moves across file systems ---
+
+// renameFile renames a file; tests replace it to simulate moves across file systems.
+var renameFile = os.Rename
+
+// moveFile renames src to dst. When they are on different file systems, where a rename is not
+// possible, src is copied and verified and only then removed.
+func moveFile(src, dst string) error {
+	err := renameFile(src, dst)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	if err := copyVerified(src, dst); err != nil {
+		return fmt.Errorf("moving %s across file systems: %w", src, err)
+	}
+	if err := os.Remove(src); err != nil {
+		// The file is safe at dst; only the original is left behind.
+		log.Printf("Error removing moved file: %v", err)
+	}
+	return nil
+}
+
+// copyVerified copies src to dst through a temporary file next to dst. The copy is synced to
+// disk, its checksum compared with the source's and it is given the source's permissions and
+// modification time before it is renamed into place. The temporary file is removed on failure.
+func copyVerified(src, dst string) (err error) {
+	source, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	stat, err := source.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".*.partial")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	// Hash the source while copying so it is read only once.
+	sourceHash := sha256.New()
+	buf := make([]byte, 64*1024)
+	if _, err = io.CopyBuffer(tmp, io.TeeReader(source, sourceHash), buf); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	copyHash, err := hashFile(tmp.Name())
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(copyHash, sourceHash.Sum(nil)) {
+		return fmt.Errorf("checksum of copy %s does not match", dst)
+	}
+
+	if err = os.Chmod(tmp.Name(), stat.Mode().Perm()); err != nil {
+		return err
+	}
+	if err = os.Chtimes(tmp.Name(), stat.ModTime(), stat.ModTime()); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
@@ -0,0 +1,75 @@
+package organizer
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// testCrossDevice makes every rename fail as if src and dst were on different file systems.
+func testCrossDevice(t *testing.T) {
+	t.Helper()
+	renameFile = func(src, dst string) error {
+		return &os.LinkError{Op: "rename", Old: src, New: dst, Err: syscall.EXDEV}
+	}
+	t.Cleanup(func() { renameFile = os.Rename })
+}
+
+func TestMoveAcrossFileSystems(t *testing.T) {
+	testCrossDevice(t)
+	dir := t.TempDir()
+	src := filepath.Join(dir, "IMG_0001.JPG")
+	dst := filepath.Join(dir, "generated", "20230501", "IMG_0001.JPG")
+	if err := os.WriteFile(src, []byte("photo"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2023, 5, 1, 14, 30, 0, 0, time.UTC)
+	if err := os.Chtimes(src, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	org := NewOrganizer(dir, "ymd", "generated", "seq", "0", "move")
+	if _, err := org.move(src, dst); err != nil {
+		t.Fatalf("move returned error: %v", err)
+	}
+
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Errorf("Expected the source to be removed, got %v", err)
+	}
+	stat, err := os.Stat(dst)
+	if err != nil {
+		t.Fatalf("Expected the moved file: %v", err)
+	}
+	if got, _ := os.ReadFile(dst); string(got) != "photo" {
+		t.Errorf("Moved file holds %q", got)
+	}
+	if !stat.ModTime().Equal(mtime) || stat.Mode().Perm() != 0640 {
+		t.Errorf("Expected mtime %v and mode 0640, got %v and %v", mtime, stat.ModTime(), stat.Mode().Perm())
+	}
+}
+
+func TestMoveAcrossFileSystemsFailure(t *testing.T) {
+	testCrossDevice(t)
+	dir := t.TempDir()
+	// Reading a directory fails halfway through the copy.
+	src := filepath.Join(dir, "album")
+	if err := os.Mkdir(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+	dstDir := filepath.Join(dir, "generated")
+	if err := os.Mkdir(dstDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := moveFile(src, filepath.Join(dstDir, "album")); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if entries, _ := os.ReadDir(dstDir); len(entries) != 0 {
+		t.Errorf("Expected no partial files, found %d entries", len(entries))
+	}
+	if _, err := os.Stat(src); err != nil {
+		t.Errorf("Expected the source to be kept: %v", err)
+	}
+}
@@ -0,0 +1,201 @@
+package organizer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Date source fallback chain ---
+
+// Steps of the date chain, in the order of DefaultDateChain.
+const (
+	DateStepXMP          = "xmp"
+	DateStepExifOriginal = "exif-original"
+	DateStepExifCreate   = "exif-create"
+	DateStepExifDateTime = "exif-datetime"
+	DateStepVideo        = "video"
+	DateStepFilename     = "filename"
+	DateStepFolder       = "folder"
+	DateStepModTime      = "mtime"
+)
+
+// Date sources used by the steps that do not read file content.
+const (
+	DateSourceFilename DateSource = "filename"
+	DateSourceFolder   DateSource = "folder"
+	DateSourceModTime  DateSource = "file:mtime"
+)
+
+// DefaultDateChain is the date chain used by organizers that do not set their own.
+var DefaultDateChain = []string{
+	DateStepXMP,
+	DateStepExifOriginal,
+	DateStepExifCreate,
+	DateStepExifDateTime,
+	DateStepVideo,
+	DateStepTakeout,
+	DateStepFilename,
+	DateStepFolder,
+	DateStepModTime,
+}
+
+// nameDatePattern finds a date, optionally followed by a time, inside a file or folder name.
+var nameDatePattern = regexp.MustCompile(`(?:^|\D)((?:19|20)\d{2})[-_.]?(0[1-9]|1[0-2])[-_.]?(0[1-9]|[12]\d|3[01])(?:[-_ T.]?([01]\d|2[0-3])[-_.:]?([0-5]\d)[-_.:]?([0-5]\d))?(?:\D|$)`)
+
+// folderMonthPattern finds a year and month inside a folder name such as "2023-05 Holidays".
+var folderMonthPattern = regexp.MustCompile(`(?:^|\D)((?:19|20)\d{2})[-_. ](0[1-9]|1[0-2])(?:\D|$)`)
+
+// ParseDateChain parses a comma-separated list of date chain steps.
+func ParseDateChain(s string) ([]string, error) {
+	var chain []string
+	for _, step := range strings.Split(s, ",") {
+		step = strings.TrimSpace(step)
+		if step == "" {
+			continue
+		}
+		if !isDateStep(step) {
+			return nil, fmt.Errorf("unknown date source %q", step)
+		}
+		chain = append(chain, step)
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("empty date source chain")
+	}
+	return chain, nil
+}
+
+// isDateStep reports whether step is a known date chain step.
+func isDateStep(step string) bool {
+	for _, known := range DefaultDateChain {
+		if step == known {
+			return true
+		}
+	}
+	return false
+}
+
+// dateSourceStep returns the chain step a date source of the metadata belongs to.
+// Sources of custom extractors count as the original capture date.
+func dateSourceStep(source DateSource) string {
+	switch source {
+	case DateSourceSidecarOriginal, DateSourceSidecarCreateDate, DateSourceSidecarDateCreated:
+		return DateStepXMP
+	case DateSourceExifDigitized, DateSourceXMPCreateDate, DateSourceXMPDateCreated:
+		return DateStepExifCreate
+	case DateSourceExifDateTime, DateSourcePNGCreationTime, DateSourceExifGPS:
+		return DateStepExifDateTime
+	case DateSourceQuickTimeCreationDate, DateSourceQuickTimeDay, DateSourceQuickTimeMovieHeader, DateSourceQuickTimeTrackHeader:
+		return DateStepVideo
+	default:
+		return DateStepExifOriginal
+	}
+}
+
+// dateChain returns the organizer's date chain or the default one.
+func (o *Organizer) dateChain() []string {
+	if len(o.DateChain) > 0 {
+		return o.DateChain
+	}
+	return DefaultDateChain
+}
+
+// countDateSource records that a file took its capture date from source.
+func (o *Organizer) countDateSource(source DateSource) {
+	if o.dateSources == nil {
+		o.dateSources = make(map[DateSource]int)
+	}
+	o.dateSources[source]++
+}
+
+// resolveDate reads a media file's metadata and walks the date chain until a step yields
+// a capture time, which the organizer's clock rules then correct. Files no extractor
+// supports are rejected with ErrUnsupportedMedia.
+func (o *Organizer) resolveDate(filePath string) (*MediaInfo, error) {
+	info, err := o.readMediaInfo(filePath)
+	if errors.Is(err, ErrUnsupportedMedia) {
+		return nil, err
+	}
+	if info == nil {
+		info = &MediaInfo{}
+	}
+	if sidecar, owned := findTakeoutSidecar(filePath); owned {
+		info.Sidecars = append(info.Sidecars, sidecar)
+	}
+
+	for _, step := range o.dateChain() {
+		candidate, ok := o.dateFromStep(step, filePath, info)
+		if !ok {
+			continue
+		}
+		info.CaptureTime, info.Timezone, info.Instant, info.DateSource = candidate.Time, candidate.Timezone, candidate.Instant, candidate.Source
+		o.correctClock(filePath, info)
+		return info, nil
+	}
+	return info, ErrNoCaptureDate
+}
+
+// dateFromStep returns the date a single chain step yields for a file.
+func (o *Organizer) dateFromStep(step, filePath string, info *MediaInfo) (DateCandidate, bool) {
+	switch step {
+	case DateStepXMP:
+		// The dates are ranked with XMPPrecedence: an embedded date ranked before the sidecar
+		// dates wins, and the steps reading it date the file.
+		if len(info.Dates) > 0 && dateSourceStep(info.Dates[0].Source) == DateStepXMP {
+			return info.Dates[0], true
+		}
+	case DateStepTakeout:
+		return takeoutDate(filePath, info)
+	case DateStepFilename:
+		return o.filenameDate(filePath)
+	case DateStepFolder:
+		if t, ok := parseFolderDate(filepath.Base(filepath.Dir(filePath))); ok {
+			return DateCandidate{Time: t, Source: DateSourceFolder}, true
+		}
+	case DateStepModTime:
+		if stat, err := os.Stat(filePath); err == nil {
+			return DateCandidate{Time: stat.ModTime(), Instant: true, Source: DateSourceModTime}, true
+		}
+	default:
+		for _, candidate := range info.Dates {
+			if dateSourceStep(candidate.Source) == step {
+				return candidate, true
+			}
+		}
+	}
+	return DateCandidate{}, false
+}
+
+// parseNameDate finds a date such as 20230501, 2023-05-01 or 2023_05_01_143000 in a name.
+func parseNameDate(name string) (time.Time, bool) {
+	for _, m := range nameDatePattern.FindAllStringSubmatch(name, -1) {
+		values := make([]int, 6)
+		for i := range values {
+			values[i], _ = strconv.Atoi(m[i+1])
+		}
+		t := time.Date(values[0], time.Month(values[1]), values[2], values[3], values[4], values[5], 0, time.UTC)
+		// Reject impossible dates such as 20230231, which time.Date would normalise.
+		if t.Day() == values[2] {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseFolderDate finds a full date or a year and month in a folder name.
+func parseFolderDate(name string) (time.Time, bool) {
+	if t, ok := parseNameDate(name); ok {
+		return t, true
+	}
+	if m := folderMonthPattern.FindStringSubmatch(name); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		return time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC), true
+	}
+	return time.Time{}, false
+}
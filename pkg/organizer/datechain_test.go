@@ -0,0 +1,145 @@
+package organizer
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testChainRegistry returns a registry whose ".xyz" extractor yields the given candidates.
+func testChainRegistry(candidates ...DateCandidate) *Registry {
+	registry := NewRegistry()
+	registry.Register("application/x-test", ExtractorFunc(func(r io.ReadSeeker) (*MediaInfo, error) {
+		info := &MediaInfo{}
+		return info, info.setDates(candidates)
+	}), ".xyz")
+	return registry
+}
+
+func TestParseDateChain(t *testing.T) {
+	chain, err := ParseDateChain(" filename, exif-original ,mtime")
+	if err != nil {
+		t.Fatalf("ParseDateChain returned error: %v", err)
+	}
+	if len(chain) != 3 || chain[0] != DateStepFilename || chain[1] != DateStepExifOriginal || chain[2] != DateStepModTime {
+		t.Errorf("Unexpected chain %v", chain)
+	}
+
+	for _, s := range []string{"exif-original,gps", ""} {
+		if _, err := ParseDateChain(s); err == nil {
+			t.Errorf("Expected error for chain %q", s)
+		}
+	}
+}
+
+func TestParseNameDate(t *testing.T) {
+	tests := []struct {
+		name string
+		want time.Time
+		ok   bool
+	}{
+		{"IMG_20230501_143000", time.Date(2023, 5, 1, 14, 30, 0, 0, time.UTC), true},
+		{"2023-05-01 party", time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC), true},
+		{"scan_2019.12.24", time.Date(2019, 12, 24, 0, 0, 0, 0, time.UTC), true},
+		{"IMG_20230231", time.Time{}, false},
+		{"DSC01234", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseNameDate(tt.name)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("parseNameDate(%q) = %s, %v; want %s, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	if got, ok := parseFolderDate("2023-05 Holidays"); !ok || !got.Equal(time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("parseFolderDate returned %s, %v", got, ok)
+	}
+}
+
+func TestResolveDateChainOrder(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "IMG_20200101_000000.xyz")
+	if err := os.WriteFile(filePath, []byte("custom"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	original := time.Date(2023, 5, 1, 14, 30, 0, 0, time.UTC)
+	digitized := time.Date(2023, 5, 2, 9, 0, 0, 0, time.UTC)
+	org := NewOrganizer(dir, "ymd", "generated", "seq", "0", "copy")
+	org.Extractors = testChainRegistry(
+		DateCandidate{Time: digitized, Source: DateSourceExifDigitized},
+		DateCandidate{Time: original, Source: DateSourceExifOriginal},
+	)
+
+	tests := []struct {
+		chain  []string
+		want   time.Time
+		source DateSource
+	}{
+		{nil, original, DateSourceExifOriginal},
+		{[]string{DateStepExifCreate, DateStepExifOriginal}, digitized, DateSourceExifDigitized},
+		{[]string{DateStepVideo, DateStepFilename}, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), DateSourceFilename},
+	}
+	for _, tt := range tests {
+		org.DateChain = tt.chain
+		info, err := org.resolveDate(filePath)
+		if err != nil {
+			t.Fatalf("resolveDate with chain %v returned error: %v", tt.chain, err)
+		}
+		if info.DateSource != tt.source || !info.CaptureTime.Equal(tt.want) {
+			t.Errorf("Chain %v: got %s from %s, want %s from %s", tt.chain, info.CaptureTime, info.DateSource, tt.want, tt.source)
+		}
+	}
+}
+
+func TestResolveDateFallbacks(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "2021-07 Trip")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatalf("Failed to create folder: %v", err)
+	}
+	filePath := filepath.Join(dir, "DSC0001.xyz")
+	if err := os.WriteFile(filePath, []byte("custom"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	modTime := time.Date(2022, 3, 4, 5, 6, 7, 0, time.Local)
+	if err := os.Chtimes(filePath, modTime, modTime); err != nil {
+		t.Fatalf("Failed to set modification time: %v", err)
+	}
+
+	org := NewOrganizer(dir, "ymd", "generated", "seq", "0", "copy")
+	org.Extractors = testChainRegistry()
+
+	info, err := org.resolveDate(filePath)
+	if err != nil {
+		t.Fatalf("resolveDate returned error: %v", err)
+	}
+	if info.DateSource != DateSourceFolder || !info.CaptureTime.Equal(time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected folder date, got %s from %s", info.CaptureTime, info.DateSource)
+	}
+
+	org.DateChain = []string{DateStepExifOriginal, DateStepModTime}
+	info, err = org.resolveDate(filePath)
+	if err != nil {
+		t.Fatalf("resolveDate returned error: %v", err)
+	}
+	if info.DateSource != DateSourceModTime || !info.CaptureTime.Equal(modTime) {
+		t.Errorf("Expected modification time, got %s from %s", info.CaptureTime, info.DateSource)
+	}
+
+	org.DateChain = []string{DateStepExifOriginal, DateStepFilename}
+	if _, err := org.resolveDate(filePath); !errors.Is(err, ErrNoCaptureDate) {
+		t.Errorf("Expected ErrNoCaptureDate, got %v", err)
+	}
+
+	textPath := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(textPath, []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	org.DateChain = nil
+	if _, err := org.resolveDate(textPath); !errors.Is(err, ErrUnsupportedMedia) {
+		t.Errorf("Expected ErrUnsupportedMedia for unsupported file, got %v", err)
+	}
+}
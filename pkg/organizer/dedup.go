@@ -0,0 +1,423 @@
+package organizer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// --- Duplicate detection ---
+
+// Dedup modes decide what happens to a file whose exact content is already in the generated folder.
+const (
+	DedupOff        = "off"
+	DedupSkip       = "skip"       // leave the duplicate where it is
+	DedupLink       = "link"       // hard link the destination to the copy already there
+	DedupQuarantine = "quarantine" // group the duplicate into DuplicatesFolder for review
+)
+
+// DuplicatesFolder is the folder under the generated folder that receives quarantined duplicates.
+const DuplicatesFolder = "Duplicates"
+
+// partialHashSize is how much of the start and of the end of a file the prefilter hashes.
+const partialHashSize = 64 * 1024
+
+// Duplicate is a file found to have the same content as a file already in the library.
+type Duplicate struct {
+	Path     string
+	Original string // file with the same content
+	Action   string
+	NewPath  string // link or quarantined file; empty when skipped
+	Size     int64
+}
+
+// dedupIndex finds files by content. Files are grouped by size, which is free to read; only
+// files of equal size are hashed, first partially and then in full.
+type dedupIndex struct {
+	bySize  map[int64][]string
+	partial map[string][]byte
+	full    map[string][]byte
+	pending map[string]string // indexed path -> file holding its content, for files not there yet
+	claims  map[string]dedupClaim
+}
+
+// dedupClaim is a file of this run that found no copy in the index and is being placed. It is
+// indexed at its source until then, so an identical file of the same batch finds it and waits
+// for it to be placed instead of being placed as well.
+type dedupClaim struct {
+	size   int64
+	placed chan struct{} // closed once the file is placed or given up
+}
+
+// ParseDedupMode checks a dedup mode; "" is DedupOff.
+func ParseDedupMode(s string) (string, error) {
+	switch s {
+	case "", DedupOff:
+		return DedupOff, nil
+	case DedupSkip, DedupLink, DedupQuarantine:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown dedup mode %q (off/skip/link/quarantine)", s)
+}
+
+// dedupEnabled reports whether duplicates are looked for.
+func (o *Organizer) dedupEnabled() bool {
+	mode, err := ParseDedupMode(o.DedupMode)
+	return err == nil && mode != DedupOff
+}
+
+// loadDedupIndex indexes the files already in the generated folder by size.
+func (o *Organizer) loadDedupIndex() {
+	o.dedupIdx = &dedupIndex{
+		bySize:  make(map[int64][]string),
+		partial: make(map[string][]byte),
+		full:    make(map[string][]byte),
+		pending: make(map[string]string),
+		claims:  make(map[string]dedupClaim),
+	}
+	root := o.destRoot()
+	quarantine := path.Join(root, DuplicatesFolder)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if p == quarantine || (p != root && strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				o.dedupIdx.add(p, info.Size())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error indexing generated folder: %v", err)
+	}
+}
+
+// add indexes a file.
+func (idx *dedupIndex) add(filePath string, size int64) {
+	idx.bySize[size] = append(idx.bySize[size], filePath)
+}
+
+// remove takes a file out of the index.
+func (idx *dedupIndex) remove(filePath string, size int64) {
+	files := idx.bySize[size]
+	for i, f := range files {
+		if f == filePath {
+			idx.bySize[size] = append(files[:i:i], files[i+1:]...)
+			break
+		}
+	}
+	delete(idx.partial, filePath)
+	delete(idx.full, filePath)
+	delete(idx.pending, filePath)
+}
+
+// find returns an indexed file with the same content as filePath.
+func (idx *dedupIndex) find(filePath string, size int64) (string, bool) {
+	candidates := idx.bySize[size]
+	if len(candidates) == 0 {
+		return "", false
+	}
+	partial, err := partialHash(filePath, size)
+	if err != nil {
+		log.Printf("Error hashing file: %v", err)
+		return "", false
+	}
+	var full []byte
+	for _, candidate := range candidates {
+		if candidate == filePath {
+			continue
+		}
+		candidatePartial, err := idx.hash(idx.partial, candidate, func(p string) ([]byte, error) { return partialHash(p, size) })
+		if err != nil || !bytes.Equal(partial, candidatePartial) {
+			continue
+		}
+		if size <= 2*partialHashSize {
+			// The partial hash already covered the whole file.
+			return candidate, true
+		}
+		if full == nil {
+			if full, err = hashFile(filePath); err != nil {
+				log.Printf("Error hashing file: %v", err)
+				return "", false
+			}
+		}
+		candidateFull, err := idx.hash(idx.full, candidate, hashFile)
+		if err == nil && bytes.Equal(full, candidateFull) {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// hash returns the cached hash of an indexed file, computing it on first use.
+func (idx *dedupIndex) hash(cache map[string][]byte, filePath string, compute func(string) ([]byte, error)) ([]byte, error) {
+	if h, ok := cache[filePath]; ok {
+		return h, nil
+	}
+	content := filePath
+	if source, ok := idx.pending[filePath]; ok {
+		content = source
+	}
+	h, err := compute(content)
+	if err != nil {
+		return nil, err
+	}
+	cache[filePath] = h
+	return h, nil
+}
+
+// partialHash hashes the first and the last partialHashSize bytes of a file.
+func partialHash(filePath string, size int64) ([]byte, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.CopyN(h, f, partialHashSize); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if size > 2*partialHashSize {
+		if _, err := f.Seek(-partialHashSize, io.SeekEnd); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// dedup looks for a file with the same content in the generated folder and, when there is one,
+// handles the duplicate according to DedupMode. It reports whether the file was handled.
+func (o *Organizer) dedup(fileEntry FileData) bool {
+	if !o.dedupEnabled() {
+		return false
+	}
+	stat, err := os.Stat(fileEntry.Path)
+	if err != nil {
+		return false
+	}
+
+	// Looking the file up and claiming its content happen under one lock, so of two identical
+	// files only one is placed.
+	o.dedupMu.Lock()
+	if o.dedupIdx == nil {
+		o.loadDedupIndex()
+	}
+	var original string
+	var found bool
+	for {
+		original, found = o.dedupIdx.find(fileEntry.Path, stat.Size())
+		claim, inFlight := o.dedupIdx.claims[original]
+		if !found || !inFlight {
+			break
+		}
+		// An identical file is being placed; it is the original once it is there.
+		o.dedupMu.Unlock()
+		<-claim.placed
+		o.dedupMu.Lock()
+	}
+	if !found {
+		o.dedupIdx.add(fileEntry.Path, stat.Size())
+		o.dedupIdx.claims[fileEntry.Path] = dedupClaim{size: stat.Size(), placed: make(chan struct{})}
+	}
+	o.dedupMu.Unlock()
+	if !found {
+		return false
+	}
+
+	duplicate := Duplicate{Path: fileEntry.Path, Original: original, Action: o.DedupMode, Size: stat.Size()}
+	switch o.DedupMode {
+	case DedupLink:
+		if original == fileEntry.NewPath {
+			// The duplicate would become a link to itself.
+			duplicate.Action = DedupSkip
+			break
+		}
+		collision, ok := o.resolveCollision(fileEntry)
+		if !ok {
+			duplicate.Action = DedupSkip
+			break
+		}
+		newPath := collision.NewPath
+		err := o.perform(PlanAction{
+			Action:      ActionLink,
+			Source:      original,
+			Destination: newPath,
+			DateSource:  fileEntry.DateSource,
+			Decision:    joinDecisions("duplicate of "+fileEntry.Path, collision.decision()),
+			Overwrite:   collision.Action == CollisionOverwrite,
+		})
+		o.settleClaim(FileData{Path: fileEntry.Path, NewPath: newPath}, err == nil)
+		if err != nil {
+			log.Printf("Error linking duplicate: %v", err)
+			return true
+		}
+		duplicate.NewPath = newPath
+		if o.GroupMode == "move" {
+			if err := o.perform(PlanAction{Action: ActionRemove, Source: fileEntry.Path, Destination: original, Decision: "duplicate of " + original}); err != nil {
+				log.Printf("Error removing duplicate: %v", err)
+			}
+		}
+		fileEntry.NewPath = newPath
+		o.groupSidecars(fileEntry)
+	case DedupQuarantine:
+		source := o.sourceOf(fileEntry.Path)
+		rel, err := filepath.Rel(source, fileEntry.Path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			rel = filepath.Base(fileEntry.Path)
+		}
+		if len(o.Sources) > 0 {
+			// Keep the duplicates of different sources apart.
+			rel = filepath.Join(filepath.Base(source), rel)
+		}
+		fileEntry.NewPath = path.Join(o.destRoot(), DuplicatesFolder, filepath.ToSlash(rel))
+		collision, ok := o.resolveCollision(fileEntry)
+		if !ok {
+			duplicate.Action = DedupSkip
+			break
+		}
+		fileEntry.NewPath = collision.NewPath
+		placed := o.transfer(fileEntry, joinDecisions("duplicate of "+original, collision.decision()), collision.Action == CollisionOverwrite)
+		o.settleClaim(fileEntry, placed)
+		if !placed {
+			return true
+		}
+		duplicate.NewPath = collision.NewPath
+	default:
+		duplicate.Action = DedupSkip
+	}
+	if duplicate.Action == DedupSkip {
+		o.perform(PlanAction{Action: ActionSkip, Source: fileEntry.Path, Destination: original, DateSource: fileEntry.DateSource, Decision: "duplicate of " + original})
+	}
+
+	if o.VerboseMode == "1" {
+		fmt.Printf("Duplicate %s of %s: %s\n", fileEntry.Path, original, duplicate.Action)
+	}
+	o.dedupMu.Lock()
+	o.duplicates = append(o.duplicates, duplicate)
+	o.dedupMu.Unlock()
+	return true
+}
+
+// indexGrouped settles the claim dedup made for a file: a placed file is indexed at its
+// destination, and the identical files waiting for it look again.
+func (o *Organizer) indexGrouped(fileEntry FileData, placed bool) {
+	if !o.dedupEnabled() {
+		return
+	}
+	content := fileEntry.NewPath
+	if o.DryRun {
+		// Nothing was copied; the file is still at its source.
+		content = fileEntry.Path
+	}
+	o.dedupMu.Lock()
+	defer o.dedupMu.Unlock()
+	if o.dedupIdx == nil {
+		return
+	}
+	if claim, ok := o.dedupIdx.claims[fileEntry.Path]; ok {
+		partial, full := o.dedupIdx.partial[fileEntry.Path], o.dedupIdx.full[fileEntry.Path]
+		o.dedupIdx.remove(fileEntry.Path, claim.size)
+		delete(o.dedupIdx.claims, fileEntry.Path)
+		defer close(claim.placed)
+		if placed {
+			// The content did not change on the way.
+			if partial != nil {
+				o.dedupIdx.partial[fileEntry.NewPath] = partial
+			}
+			if full != nil {
+				o.dedupIdx.full[fileEntry.NewPath] = full
+			}
+		}
+	}
+	if !placed {
+		return
+	}
+	stat, err := os.Stat(content)
+	if err != nil {
+		return
+	}
+	o.dedupIdx.add(fileEntry.NewPath, stat.Size())
+	if content != fileEntry.NewPath {
+		o.dedupIdx.pending[fileEntry.NewPath] = content
+	}
+}
+
+// linkFile hard links dst to src, or symlinks it where hard links are not possible. An existing
+// dst, which the collision policy chose to overwrite, is replaced.
+func linkFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	abs, err := filepath.Abs(src)
+	if err != nil {
+		return err
+	}
+	return os.Symlink(abs, dst)
+}
+
+// Duplicates returns the duplicates found in the current run.
+func (o *Organizer) Duplicates() []Duplicate {
+	return o.duplicates
+}
+
+// printDuplicates reports the duplicates found and the space they did not take up again.
+// Quarantined duplicates still take up space until they are deleted.
+func (o *Organizer) printDuplicates() {
+	if len(o.duplicates) == 0 {
+		return
+	}
+	var reclaimed, quarantined int64
+	for _, d := range o.duplicates {
+		if d.Action == DedupQuarantine {
+			quarantined += d.Size
+		} else {
+			reclaimed += d.Size
+		}
+	}
+	fmt.Printf("Found %d duplicates, %s reclaimed, %s quarantined:\n", len(o.duplicates), formatBytes(reclaimed), formatBytes(quarantined))
+	for _, d := range o.duplicates {
+		line := fmt.Sprintf("  %s = %s: %s", d.Path, d.Original, d.Action)
+		if d.NewPath != "" {
+			line += " " + d.NewPath
+		}
+		fmt.Println(line)
+	}
+}
+
+// formatBytes formats a size in bytes with a binary unit, such as "1.5 MiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
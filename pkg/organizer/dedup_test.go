@@ -0,0 +1,113 @@
+package organizer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDedupModes(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 20*1024) // larger than the partial hash
+	changed := append([]byte(nil), content...)
+	changed[len(changed)/2] = 'x' // differs only where the prefilter does not look
+
+	for _, mode := range []string{DedupSkip, DedupLink, DedupQuarantine} {
+		dir := t.TempDir()
+		existing := filepath.Join(dir, "generated", "20230101", "IMG_0001.xyz")
+		files := map[string][]byte{
+			existing: content,
+			filepath.Join(dir, "IMG_20230501_143000.xyz"): content,
+			filepath.Join(dir, "IMG_20230502_143000.xyz"): changed,
+			filepath.Join(dir, "IMG_20230503_143000.xyz"): []byte("unique"),
+		}
+		for name, data := range files {
+			if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(name, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		org := NewOrganizer(dir, "ymd", "generated", "seq", "0", "move")
+		org.Extractors = testChainRegistry()
+		org.DedupMode = mode
+		org.AddFileEntries(dir)
+		org.OrganizeFiles(0)
+
+		for _, name := range []string{"20230502/IMG_20230502_143000.xyz", "20230503/IMG_20230503_143000.xyz"} {
+			if _, err := os.Stat(filepath.Join(dir, "generated", filepath.FromSlash(name))); err != nil {
+				t.Errorf("Mode %s: expected %s to be grouped: %v", mode, name, err)
+			}
+		}
+
+		duplicates := org.Duplicates()
+		if len(duplicates) != 1 || duplicates[0].Original != existing || duplicates[0].Size != int64(len(content)) {
+			t.Fatalf("Mode %s: unexpected duplicates %+v", mode, duplicates)
+		}
+
+		_, srcErr := os.Stat(filepath.Join(dir, "IMG_20230501_143000.xyz"))
+		switch mode {
+		case DedupSkip:
+			if srcErr != nil {
+				t.Errorf("Mode %s: expected the duplicate to stay in place: %v", mode, srcErr)
+			}
+		case DedupLink:
+			a, _ := os.Stat(existing)
+			b, err := os.Stat(filepath.Join(dir, "generated", "20230501", "IMG_20230501_143000.xyz"))
+			if err != nil || !os.SameFile(a, b) {
+				t.Errorf("Mode %s: expected the destination to link to the existing file: %v", mode, err)
+			}
+			if srcErr == nil {
+				t.Errorf("Mode %s: expected the duplicate to be removed from the source", mode)
+			}
+		case DedupQuarantine:
+			if _, err := os.Stat(filepath.Join(dir, "generated", DuplicatesFolder, "IMG_20230501_143000.xyz")); err != nil {
+				t.Errorf("Mode %s: expected the duplicate in quarantine: %v", mode, err)
+			}
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for n, want := range map[int64]string{512: "512 B", 1536: "1.5 KiB", 3 << 30: "3.0 GiB"} {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestDedupSameBatch(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 20*1024)
+	for run := 0; run < 10; run++ {
+		dir := t.TempDir()
+		var names []string
+		for day := 1; day <= 16; day++ {
+			name := fmt.Sprintf("IMG_202305%02d_143000.xyz", day)
+			if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+				t.Fatal(err)
+			}
+			names = append(names, name)
+		}
+
+		// The identical files are grouped concurrently in one batch.
+		org := NewOrganizer(dir, "ymd", "generated", "con", "0", "copy")
+		org.Extractors = testChainRegistry()
+		org.DedupMode = DedupSkip
+		org.scanForDateFolders(dir)
+		org.createFolders()
+		org.ProcessFilesInBatches(dir, 4)
+
+		grouped := 0
+		for _, name := range names {
+			if _, err := os.Stat(filepath.Join(dir, "generated", name[4:12], name)); err == nil {
+				grouped++
+			}
+		}
+		if duplicates := org.Duplicates(); grouped != 1 || len(duplicates) != len(names)-1 {
+			t.Fatalf("Expected one copy grouped and the others found as duplicates, got %d grouped and %+v", grouped, duplicates)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package organizer
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Capture dates from file names ---
+
+// FilenamePattern finds a capture date in a file name. The expression uses the named groups
+// year, month and day, optionally hour, minute, second and ms, or a single unix or unixms group
+// holding an epoch timestamp.
+type FilenamePattern struct {
+	Name   string
+	Regexp *regexp.Regexp
+}
+
+// filenameDateGroups lists the named groups a FilenamePattern may use.
+var filenameDateGroups = []string{"year", "month", "day", "hour", "minute", "second", "ms", "unix", "unixms"}
+
+// BuiltinFilenamePatterns covers the naming schemes of common phones, messengers and apps.
+// They are tried in order after the organizer's own FilenamePatterns.
+var BuiltinFilenamePatterns = []FilenamePattern{
+	// Pixel: PXL_20230501_143000123.jpg, PXL_20230501_143000123.MP.jpg
+	mustFilenamePattern("pixel", `^PXL_(?P<year>\d{4})(?P<month>\d{2})(?P<day>\d{2})_(?P<hour>\d{2})(?P<minute>\d{2})(?P<second>\d{2})(?P<ms>\d{3})?`),
+	// WhatsApp: IMG-20230501-WA0003.jpg, VID-20230501-WA0003.mp4, AUD-..., PTT-...
+	mustFilenamePattern("whatsapp", `^(?:IMG|VID|AUD|PTT|STK|DOC)-(?P<year>\d{4})(?P<month>\d{2})(?P<day>\d{2})-WA\d+`),
+	// Android and Samsung cameras: IMG_20230501_143000.jpg, VID_20230501_143000.mp4, 20230501_143000.jpg
+	mustFilenamePattern("camera", `^(?:(?:IMG|VID|MVIMG|PANO|BURST\d*|DJI)_)?(?P<year>\d{4})(?P<month>\d{2})(?P<day>\d{2})_(?P<hour>\d{2})(?P<minute>\d{2})(?P<second>\d{2})(?P<ms>\d{3})?`),
+	// Screenshots: Screenshot_2023-05-01-14-30-00.png, Screenshot_20230501-143000.png,
+	// Screenshot 2023-05-01 at 14.30.00.png
+	mustFilenamePattern("screenshot", `^Screen ?[Ss]hot[_ -](?P<year>\d{4})-?(?P<month>\d{2})-?(?P<day>\d{2})(?:[-_ ]|\sat\s)(?P<hour>\d{2})[-.]?(?P<minute>\d{2})[-.]?(?P<second>\d{2})`),
+	// Signal: signal-2023-05-01-14-30-00-123.jpg, signal-2023-05-01-143000.jpg
+	mustFilenamePattern("signal", `^signal-(?P<year>\d{4})-(?P<month>\d{2})-(?P<day>\d{2})-(?P<hour>\d{2})-?(?P<minute>\d{2})-?(?P<second>\d{2})(?:-(?P<ms>\d{3}))?`),
+	// Telegram and Dropbox camera uploads: photo_2023-05-01_14-30-00.jpg, 2023-05-01 14.30.00.jpg
+	mustFilenamePattern("dated", `^(?:photo_|video_)?(?P<year>\d{4})-(?P<month>\d{2})-(?P<day>\d{2})[_ ](?P<hour>\d{2})[-.](?P<minute>\d{2})[-.](?P<second>\d{2})`),
+	// Facebook and other apps that name files after a millisecond timestamp: FB_IMG_1682951400000.jpg
+	mustFilenamePattern("timestamp", `^(?:FB_IMG_|received_)?(?P<unixms>1\d{12})(?:\D|$)`),
+}
+
+// ParseFilenamePattern compiles a user-supplied file name pattern. The expression must have
+// year, month and day groups or a unix/unixms group.
+func ParseFilenamePattern(expr string) (FilenamePattern, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return FilenamePattern{}, err
+	}
+
+	groups := make(map[string]bool)
+	for _, name := range re.SubexpNames() {
+		if name == "" {
+			continue
+		}
+		if !isFilenameDateGroup(name) {
+			return FilenamePattern{}, fmt.Errorf("unknown group %q in pattern %q", name, expr)
+		}
+		groups[name] = true
+	}
+	if !(groups["year"] && groups["month"] && groups["day"]) && !groups["unix"] && !groups["unixms"] {
+		return FilenamePattern{}, fmt.Errorf("pattern %q needs year, month and day groups or a unix timestamp group", expr)
+	}
+	return FilenamePattern{Name: expr, Regexp: re}, nil
+}
+
+// mustFilenamePattern is ParseFilenamePattern for the built-in library.
+func mustFilenamePattern(name, expr string) FilenamePattern {
+	pattern, err := ParseFilenamePattern(expr)
+	if err != nil {
+		panic(err)
+	}
+	pattern.Name = name
+	return pattern
+}
+
+// isFilenameDateGroup reports whether name is a group FilenamePattern understands.
+func isFilenameDateGroup(name string) bool {
+	for _, group := range filenameDateGroups {
+		if name == group {
+			return true
+		}
+	}
+	return false
+}
+
+// Match returns the date the pattern finds in a file name.
+func (p FilenamePattern) Match(name string) (time.Time, bool) {
+	candidate, ok := p.match(name)
+	return candidate.Time, ok
+}
+
+// match returns the date the pattern finds in a file name as a date candidate.
+// Epoch timestamps are exact instants, other dates are wall clock times.
+func (p FilenamePattern) match(name string) (DateCandidate, bool) {
+	m := p.Regexp.FindStringSubmatch(name)
+	if m == nil {
+		return DateCandidate{}, false
+	}
+
+	values := make(map[string]int)
+	for i, group := range p.Regexp.SubexpNames() {
+		if group == "" || m[i] == "" {
+			continue
+		}
+		value, err := strconv.ParseInt(m[i], 10, 64)
+		if err != nil {
+			return DateCandidate{}, false
+		}
+		if group == "unix" || group == "unixms" {
+			// An epoch timestamp is an instant, so show it in local time like other UTC sources.
+			t := time.Unix(value, 0)
+			if group == "unixms" {
+				t = time.UnixMilli(value)
+			}
+			return DateCandidate{Time: t.In(time.Local), Instant: true, Source: DateSourceFilename}, true
+		}
+		values[group] = int(value)
+	}
+
+	year, month, day := values["year"], values["month"], values["day"]
+	if year < 1900 || month < 1 || month > 12 || day < 1 || values["hour"] > 23 || values["minute"] > 59 || values["second"] > 59 {
+		return DateCandidate{}, false
+	}
+	t := time.Date(year, time.Month(month), day, values["hour"], values["minute"], values["second"], values["ms"]*int(time.Millisecond), time.UTC)
+	// Reject impossible dates such as 20230231, which time.Date would normalise.
+	if t.Day() != day {
+		return DateCandidate{}, false
+	}
+	return DateCandidate{Time: t, Source: DateSourceFilename}, true
+}
+
+// filenameDate finds a capture date in the base name of filePath, trying the organizer's
+// patterns first, then the built-in library and finally any plain date in the name.
+func (o *Organizer) filenameDate(filePath string) (DateCandidate, bool) {
+	name := filepath.Base(filePath)
+	for _, patterns := range [][]FilenamePattern{o.FilenamePatterns, BuiltinFilenamePatterns} {
+		for _, pattern := range patterns {
+			if candidate, ok := pattern.match(name); ok {
+				return candidate, true
+			}
+		}
+	}
+	t, ok := parseNameDate(strings.TrimSuffix(name, filepath.Ext(name)))
+	return DateCandidate{Time: t, Source: DateSourceFilename}, ok
+}
@@ -0,0 +1,74 @@
+package organizer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBuiltinFilenamePatterns(t *testing.T) {
+	tests := []struct {
+		name string
+		want time.Time
+	}{
+		{"IMG_20230501_143000.jpg", time.Date(2023, 5, 1, 14, 30, 0, 0, time.UTC)},
+		{"VID_20230501_143000.mp4", time.Date(2023, 5, 1, 14, 30, 0, 0, time.UTC)},
+		{"20230501_143000.jpg", time.Date(2023, 5, 1, 14, 30, 0, 0, time.UTC)},
+		{"PXL_20230501_143000123.MP.jpg", time.Date(2023, 5, 1, 14, 30, 0, 123*int(time.Millisecond), time.UTC)},
+		{"VID-20230501-WA0003.mp4", time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{"Screenshot_2023-05-01-14-30-00.png", time.Date(2023, 5, 1, 14, 30, 0, 0, time.UTC)},
+		{"Screenshot_20230501-143000.png", time.Date(2023, 5, 1, 14, 30, 0, 0, time.UTC)},
+		{"Screenshot 2023-05-01 at 14.30.00.png", time.Date(2023, 5, 1, 14, 30, 0, 0, time.UTC)},
+		{"signal-2023-05-01-14-30-00-123.jpg", time.Date(2023, 5, 1, 14, 30, 0, 123*int(time.Millisecond), time.UTC)},
+		{"photo_2023-05-01_14-30-00.jpg", time.Date(2023, 5, 1, 14, 30, 0, 0, time.UTC)},
+		{"2023-05-01 14.30.00.jpg", time.Date(2023, 5, 1, 14, 30, 0, 0, time.UTC)},
+		{"FB_IMG_1682951400000.jpg", time.UnixMilli(1682951400000)},
+		{"holiday 2023-05-01.jpg", time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	org := NewOrganizer("", "ymd", "generated", "seq", "0", "copy")
+	for _, tt := range tests {
+		got, ok := org.filenameDate(tt.name)
+		if !ok || !got.Time.Equal(tt.want) {
+			t.Errorf("filenameDate(%q) = %s, %v; want %s", tt.name, got.Time, ok, tt.want)
+		}
+	}
+
+	for _, name := range []string{"DSC01234.JPG", "IMG_20231301_143000.jpg", "IMG-20230230-WA0001.jpg"} {
+		if got, ok := org.filenameDate(name); ok {
+			t.Errorf("filenameDate(%q) = %s, expected no date", name, got.Time)
+		}
+	}
+}
+
+func TestParseFilenamePattern(t *testing.T) {
+	pattern, err := ParseFilenamePattern(`^trip-(?P<day>\d{2})\.(?P<month>\d{2})\.(?P<year>\d{4})`)
+	if err != nil {
+		t.Fatalf("ParseFilenamePattern returned error: %v", err)
+	}
+
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "trip-24.12.2019-20230501_143000.xyz")
+	if err := os.WriteFile(filePath, []byte("custom"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	// User patterns win over the built-in library.
+	org := NewOrganizer(dir, "ymd", "generated", "seq", "0", "copy")
+	org.Extractors = testChainRegistry()
+	org.FilenamePatterns = []FilenamePattern{pattern}
+	info, err := org.resolveDate(filePath)
+	if err != nil {
+		t.Fatalf("resolveDate returned error: %v", err)
+	}
+	if want := time.Date(2019, 12, 24, 0, 0, 0, 0, time.UTC); info.DateSource != DateSourceFilename || !info.CaptureTime.Equal(want) {
+		t.Errorf("Expected %s from the filename, got %s from %s", want, info.CaptureTime, info.DateSource)
+	}
+
+	for _, expr := range []string{`(?P<year>\d{4})(?P<month>\d{2})`, `(?P<year>\d{4})(?P<month>\d{2})(?P<day>\d{2})(?P<extra>\d)`, `(`} {
+		if _, err := ParseFilenamePattern(expr); err == nil {
+			t.Errorf("Expected error for pattern %q", expr)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package organizer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// --- GIF capture dates ---
+
+// gifXMPApplication identifies the application extension that carries an XMP packet.
+const gifXMPApplication = "XMP DataXMP"
+
+// extractGIF reads the capture date from the XMP application extension of a GIF image.
+func extractGIF(r io.ReadSeeker) (*MediaInfo, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, 13)
+	if _, err := io.ReadFull(br, header); err != nil || (string(header[0:6]) != "GIF87a" && string(header[0:6]) != "GIF89a") {
+		return nil, ErrNoCaptureDate
+	}
+
+	info := &MediaInfo{
+		Width:  int(binary.LittleEndian.Uint16(header[6:8])),
+		Height: int(binary.LittleEndian.Uint16(header[8:10])),
+	}
+	if header[10]&0x80 != 0 {
+		if err := gifSkip(br, 3<<(header[10]&0x07+1)); err != nil {
+			return info, ErrNoCaptureDate
+		}
+	}
+
+	for {
+		introducer, err := br.ReadByte()
+		if err != nil {
+			return info, ErrNoCaptureDate
+		}
+
+		switch introducer {
+		case 0x21: // extension
+			label, err := br.ReadByte()
+			if err != nil {
+				return info, ErrNoCaptureDate
+			}
+			if label == 0xFF {
+				packet, isXMP, err := gifApplicationExtension(br)
+				if err != nil {
+					return info, ErrNoCaptureDate
+				}
+				if isXMP {
+					xmpInfo, err := mediaInfoFromXMP(packet)
+					if xmpInfo != nil {
+						mergeCameraInfo(xmpInfo, info)
+					}
+					return xmpInfo, err
+				}
+				continue
+			}
+			if err := gifSkipSubBlocks(br); err != nil {
+				return info, ErrNoCaptureDate
+			}
+		case 0x2C: // image descriptor
+			descriptor := make([]byte, 9)
+			if _, err := io.ReadFull(br, descriptor); err != nil {
+				return info, ErrNoCaptureDate
+			}
+			if descriptor[8]&0x80 != 0 {
+				if err := gifSkip(br, 3<<(descriptor[8]&0x07+1)); err != nil {
+					return info, ErrNoCaptureDate
+				}
+			}
+			// LZW minimum code size, then the image data sub-blocks.
+			if _, err := br.ReadByte(); err != nil {
+				return info, ErrNoCaptureDate
+			}
+			if err := gifSkipSubBlocks(br); err != nil {
+				return info, ErrNoCaptureDate
+			}
+		default: // trailer (0x3B) or garbage
+			return info, ErrNoCaptureDate
+		}
+	}
+}
+
+// gifApplicationExtension reads an application extension. XMP packets are stored
+// raw rather than in sub-blocks and end with a 257-byte "magic trailer".
+func gifApplicationExtension(br *bufio.Reader) ([]byte, bool, error) {
+	identifier := make([]byte, 12)
+	if _, err := io.ReadFull(br, identifier); err != nil {
+		return nil, false, err
+	}
+	if identifier[0] != 11 || string(identifier[1:]) != gifXMPApplication {
+		// Not XMP: the identifier block is followed by ordinary sub-blocks.
+		return nil, false, gifSkipSubBlocks(br)
+	}
+
+	var packet bytes.Buffer
+	for packet.Len() < maxPNGTextChunk {
+		b, err := br.ReadByte()
+		if err != nil {
+			return nil, false, err
+		}
+		// The magic trailer starts with 0x01 0xFF 0xFE ... and every XMP byte is ASCII/UTF-8 above 0x01.
+		if b == 0x01 {
+			if err := gifSkip(br, 257); err != nil {
+				return nil, false, err
+			}
+			return packet.Bytes(), true, nil
+		}
+		packet.WriteByte(b)
+	}
+	return packet.Bytes(), true, nil
+}
+
+// gifSkipSubBlocks skips data sub-blocks up to and including the block terminator.
+func gifSkipSubBlocks(br *bufio.Reader) error {
+	for {
+		size, err := br.ReadByte()
+		if err != nil {
+			return err
+		}
+		if size == 0 {
+			return nil
+		}
+		if err := gifSkip(br, int(size)); err != nil {
+			return err
+		}
+	}
+}
+
+// gifSkip discards n bytes.
+func gifSkip(br *bufio.Reader, n int) error {
+	_, err := br.Discard(n)
+	return err
+}
@@ -0,0 +1,69 @@
+package organizer
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testGIF builds a one-pixel GIF89a, optionally carrying an XMP packet after the image.
+func testGIF(xmp string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("GIF89a")
+	buf.Write([]byte{0x40, 0x01, 0xF0, 0x00, 0x80, 0, 0}) // 320x240, 2-entry global color table
+	buf.Write(make([]byte, 6))
+
+	// A Netscape looping extension that must be skipped.
+	buf.Write([]byte{0x21, 0xFF, 11})
+	buf.WriteString("NETSCAPE2.0")
+	buf.Write([]byte{3, 1, 0, 0, 0})
+
+	// Image descriptor, LZW code size and one data sub-block.
+	buf.Write([]byte{0x2C, 0, 0, 0, 0, 1, 0, 1, 0, 0, 2, 2, 0x44, 0x01, 0})
+
+	if xmp != "" {
+		buf.Write([]byte{0x21, 0xFF, 11})
+		buf.WriteString(gifXMPApplication)
+		buf.WriteString(xmp)
+		buf.WriteByte(0x01)
+		for i := 0xFF; i >= 0; i-- {
+			buf.WriteByte(byte(i))
+		}
+		buf.WriteByte(0)
+	}
+
+	buf.WriteByte(0x3B)
+	return buf.Bytes()
+}
+
+func TestExtractGIFXMP(t *testing.T) {
+	xmp := testXMPPacket(`xmp:CreateDate="2023-05-01T14:30:00+02:00">`)
+	info, err := extractGIF(bytes.NewReader(testGIF(xmp)))
+	if err != nil {
+		t.Fatalf("extractGIF returned error: %v", err)
+	}
+	if info.DateSource != DateSourceXMPCreateDate {
+		t.Errorf("Expected date source %s, got %s", DateSourceXMPCreateDate, info.DateSource)
+	}
+	if info.Width != 320 || info.Height != 240 {
+		t.Errorf("Expected screen size 320x240, got %dx%d", info.Width, info.Height)
+	}
+}
+
+func TestExtractGIFWithoutXMP(t *testing.T) {
+	if _, err := extractGIF(bytes.NewReader(testGIF(""))); err == nil {
+		t.Errorf("Expected an error for a GIF without XMP")
+	}
+}
+
+func TestRegistryExtractGIFFile(t *testing.T) {
+	data := testGIF(testXMPPacket(`xmp:CreateDate="2023-05-01T14:30:00">`))
+	filePath := filepath.Join(t.TempDir(), "animation.gif")
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		t.Fatalf("Failed to write GIF: %v", err)
+	}
+	if _, err := DefaultRegistry.Extract(filePath); err != nil {
+		t.Errorf("Extract returned error: %v", err)
+	}
+}
@@ -0,0 +1,246 @@
+package organizer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// --- HEIF / HEIC / AVIF capture dates ---
+
+// errNoExifItem is returned when a HEIF file has no Exif item.
+var errNoExifItem = errors.New("no Exif item")
+
+// heifExtent is one contiguous piece of an item's data.
+type heifExtent struct {
+	offset uint64
+	length uint64
+}
+
+// heifLocation tells where the data of one item is stored.
+type heifLocation struct {
+	constructionMethod uint16
+	baseOffset         uint64
+	extents            []heifExtent
+}
+
+// extractHEIF reads capture metadata from the Exif item of a HEIF, HEIC or AVIF image.
+func extractHEIF(r io.ReadSeeker) (*MediaInfo, error) {
+	meta, err := findBMFFBox(r, "meta")
+	if err != nil {
+		return nil, ErrNoCaptureDate
+	}
+	children, err := bmffChildren(r, meta)
+	if err != nil && len(children) == 0 {
+		return nil, err
+	}
+
+	rawExif, err := readHEIFExif(r, children)
+	if err != nil {
+		return nil, ErrNoCaptureDate
+	}
+
+	info, err := mediaInfoFromExif(rawExif)
+	if info != nil && info.Width == 0 {
+		info.Width, info.Height = heifImageSize(r, children)
+	}
+	return info, err
+}
+
+// readHEIFExif locates the Exif item through iinf/iloc and returns its TIFF data.
+func readHEIFExif(r io.ReadSeeker, metaChildren []bmffBox) ([]byte, error) {
+	var iinf, iloc, idat *bmffBox
+	for i := range metaChildren {
+		switch metaChildren[i].Type {
+		case "iinf":
+			iinf = &metaChildren[i]
+		case "iloc":
+			iloc = &metaChildren[i]
+		case "idat":
+			idat = &metaChildren[i]
+		}
+	}
+	if iinf == nil || iloc == nil {
+		return nil, errNoExifItem
+	}
+
+	iinfData, err := readBoxData(r, *iinf)
+	if err != nil {
+		return nil, err
+	}
+	itemID, ok := heifExifItemID(iinfData)
+	if !ok {
+		return nil, errNoExifItem
+	}
+
+	ilocData, err := readBoxData(r, *iloc)
+	if err != nil {
+		return nil, err
+	}
+	locations, err := parseHEIFLocations(ilocData)
+	if err != nil {
+		return nil, err
+	}
+	location, ok := locations[itemID]
+	if !ok {
+		return nil, errNoExifItem
+	}
+
+	var buf bytes.Buffer
+	for _, extent := range location.extents {
+		offset := int64(location.baseOffset + extent.offset)
+		switch location.constructionMethod {
+		case 0:
+		case 1:
+			if idat == nil {
+				return nil, errNoExifItem
+			}
+			offset += idat.dataOffset()
+		default:
+			return nil, errNoExifItem
+		}
+		if extent.length > maxBoxRead {
+			return nil, errNoExifItem
+		}
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if _, err := io.CopyN(&buf, r, int64(extent.length)); err != nil {
+			return nil, err
+		}
+	}
+
+	return heifExifPayload(buf.Bytes())
+}
+
+// heifExifPayload strips the TIFF header offset that prefixes a HEIF Exif item.
+func heifExifPayload(data []byte) ([]byte, error) {
+	if len(data) < 4 {
+		return nil, errNoExifItem
+	}
+	headerOffset := int(binary.BigEndian.Uint32(data[0:4]))
+	payload := data[4:]
+	if headerOffset < len(payload) {
+		payload = payload[headerOffset:]
+	}
+	// Some writers point at the "Exif\0\0" marker or get the offset wrong, so look for the TIFF header.
+	for _, marker := range [][]byte{[]byte("MM\x00*"), []byte("II*\x00")} {
+		if i := bytes.Index(payload, marker); i >= 0 {
+			return payload[i:], nil
+		}
+	}
+	return nil, errNoExifItem
+}
+
+// heifExifItemID returns the ID of the item with type "Exif" from an iinf payload.
+func heifExifItemID(data []byte) (uint32, bool) {
+	if len(data) < 6 {
+		return 0, false
+	}
+	pos := 6
+	if data[0] != 0 {
+		pos = 8
+	}
+
+	// The boxes before a broken one are still usable.
+	boxes, _ := readBMFFBoxes(bytes.NewReader(data), int64(pos), int64(len(data)))
+	for _, box := range boxes {
+		if box.Type != "infe" || box.dataOffset() > box.dataEnd() || box.dataEnd() > int64(len(data)) {
+			continue
+		}
+		infe := data[box.dataOffset():box.dataEnd()]
+		if len(infe) < 4 {
+			continue
+		}
+		var id uint32
+		var itemType string
+		switch version := infe[0]; {
+		case version == 2 && len(infe) >= 12:
+			id = uint32(binary.BigEndian.Uint16(infe[4:6]))
+			itemType = string(infe[8:12])
+		case version >= 3 && len(infe) >= 14:
+			id = binary.BigEndian.Uint32(infe[4:8])
+			itemType = string(infe[10:14])
+		default:
+			continue
+		}
+		if itemType == "Exif" {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// parseHEIFLocations parses an iloc payload into item locations keyed by item ID.
+func parseHEIFLocations(data []byte) (map[uint32]heifLocation, error) {
+	p := &byteParser{data: data}
+	version := p.uint(1)
+	p.skip(3)
+	sizes := p.uint(1)
+	offsetSize, lengthSize := int(sizes>>4), int(sizes&0x0F)
+	sizes = p.uint(1)
+	baseOffsetSize, indexSize := int(sizes>>4), 0
+	if version == 1 || version == 2 {
+		indexSize = int(sizes & 0x0F)
+	}
+
+	var itemCount uint64
+	if version < 2 {
+		itemCount = p.uint(2)
+	} else {
+		itemCount = p.uint(4)
+	}
+
+	locations := make(map[uint32]heifLocation)
+	for i := uint64(0); i < itemCount && p.err == nil; i++ {
+		var id uint32
+		if version < 2 {
+			id = uint32(p.uint(2))
+		} else {
+			id = uint32(p.uint(4))
+		}
+
+		var location heifLocation
+		if version == 1 || version == 2 {
+			location.constructionMethod = uint16(p.uint(2) & 0x0F)
+		}
+		p.skip(2) // data_reference_index
+		location.baseOffset = p.uint(baseOffsetSize)
+
+		extentCount := p.uint(2)
+		for j := uint64(0); j < extentCount && p.err == nil; j++ {
+			p.skip(indexSize)
+			location.extents = append(location.extents, heifExtent{
+				offset: p.uint(offsetSize),
+				length: p.uint(lengthSize),
+			})
+		}
+		locations[id] = location
+	}
+	return locations, p.err
+}
+
+// heifImageSize returns the largest image spatial extent (ispe) found in iprp/ipco.
+func heifImageSize(r io.ReadSeeker, metaChildren []bmffBox) (int, int) {
+	ipco, err := findBMFFPath(r, metaChildren, "iprp", "ipco")
+	if err != nil {
+		return 0, 0
+	}
+	properties, _ := bmffChildren(r, ipco)
+
+	var width, height int
+	for _, box := range properties {
+		if box.Type != "ispe" {
+			continue
+		}
+		data, err := readBoxData(r, box)
+		if err != nil || len(data) < 12 {
+			continue
+		}
+		if w := int(binary.BigEndian.Uint32(data[4:8])); w > width {
+			width, height = w, int(binary.BigEndian.Uint32(data[8:12]))
+		}
+	}
+	return width, height
+}
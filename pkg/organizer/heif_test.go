@@ -0,0 +1,125 @@
+package organizer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dsoprea/go-exif/v3"
+)
+
+// testExifBlock returns the raw EXIF block (starting at the TIFF header) of a sample image.
+func testExifBlock(t *testing.T) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(testImagesDir, "generated_sample_001.JPG"))
+	if err != nil {
+		t.Fatalf("Failed to read sample image: %v", err)
+	}
+	rawExif, err := exif.SearchAndExtractExif(data)
+	if err != nil {
+		t.Fatalf("Failed to extract sample EXIF: %v", err)
+	}
+	return rawExif
+}
+
+// testHEIFItemInfo builds an iinf box declaring an hvc1 item 1 and an Exif item 2.
+func testHEIFItemInfo() []byte {
+	infe := func(id uint16, itemType string) []byte {
+		payload := []byte{2, 0, 0, 0, byte(id >> 8), byte(id), 0, 0}
+		return testBox("infe", payload, []byte(itemType), []byte{0})
+	}
+	return testBox("iinf", []byte{0, 0, 0, 0, 0, 2}, infe(1, "hvc1"), infe(2, "Exif"))
+}
+
+// testHEIF builds a HEIC file whose Exif item is stored either in mdat or in idat.
+func testHEIF(t *testing.T, brand string, useIdat bool) []byte {
+	exifItem := append([]byte{0, 0, 0, 6}, append([]byte("Exif\x00\x00"), testExifBlock(t)...)...)
+
+	ftyp := testBox("ftyp", []byte(brand), make([]byte, 4), []byte("mif1"), []byte(brand))
+	hdlr := testBox("hdlr", make([]byte, 8), []byte("pict"), make([]byte, 13))
+
+	iloc := func(offset uint32) []byte {
+		payload := []byte{0, 0, 0, 0, 0x44, 0x00, 0, 1, 0, 2, 0, 0, 0, 1}
+		if useIdat {
+			payload = []byte{1, 0, 0, 0, 0x44, 0x00, 0, 1, 0, 2, 0, 1, 0, 0, 0, 1}
+		}
+		extent := make([]byte, 8)
+		binary.BigEndian.PutUint32(extent[0:4], offset)
+		binary.BigEndian.PutUint32(extent[4:8], uint32(len(exifItem)))
+		return testBox("iloc", payload, extent)
+	}
+
+	if useIdat {
+		meta := testBox("meta", make([]byte, 4), hdlr, testHEIFItemInfo(), iloc(0), testBox("idat", exifItem))
+		return append(ftyp, meta...)
+	}
+
+	// Build once to learn the meta size, then point the extent past the mdat header.
+	meta := testBox("meta", make([]byte, 4), hdlr, testHEIFItemInfo(), iloc(0))
+	offset := uint32(len(ftyp) + len(meta) + 8)
+	meta = testBox("meta", make([]byte, 4), hdlr, testHEIFItemInfo(), iloc(offset))
+	return bytes.Join([][]byte{ftyp, meta, testBox("mdat", exifItem)}, nil)
+}
+
+func TestExtractHEIF(t *testing.T) {
+	want, err := mediaInfoFromExif(testExifBlock(t))
+	if err != nil {
+		t.Fatalf("Failed to read sample EXIF: %v", err)
+	}
+
+	for name, useIdat := range map[string]bool{"mdat": false, "idat": true} {
+		info, err := extractHEIF(bytes.NewReader(testHEIF(t, "heic", useIdat)))
+		if err != nil {
+			t.Errorf("%s: extractHEIF returned error: %v", name, err)
+			continue
+		}
+		if !info.CaptureTime.Equal(want.CaptureTime) {
+			t.Errorf("%s: expected capture time %s, got %s", name, want.CaptureTime, info.CaptureTime)
+		}
+	}
+}
+
+func TestExtractHEIFWithoutExif(t *testing.T) {
+	ftyp := testBox("ftyp", []byte("heic"), make([]byte, 4))
+	meta := testBox("meta", make([]byte, 4), testBox("hdlr", make([]byte, 8), []byte("pict"), make([]byte, 13)))
+	if _, err := extractHEIF(bytes.NewReader(append(ftyp, meta...))); err == nil {
+		t.Errorf("Expected an error for a HEIF file without an Exif item")
+	}
+}
+
+func TestExtractHEIFBrokenItemInfo(t *testing.T) {
+	// An infe box whose 64-bit size runs far past the end of the file.
+	infe := make([]byte, 16)
+	binary.BigEndian.PutUint32(infe[0:4], 1)
+	copy(infe[4:8], "infe")
+	binary.BigEndian.PutUint64(infe[8:16], 0x7fffffffffffffff)
+	iinf := testBox("iinf", []byte{0, 0, 0, 0, 0, 1}, infe)
+
+	ftyp := testBox("ftyp", []byte("heic"), make([]byte, 4))
+	meta := testBox("meta", make([]byte, 4), testBox("hdlr", make([]byte, 8), []byte("pict"), make([]byte, 13)), iinf, testBox("iloc", make([]byte, 8)))
+	if _, err := extractHEIF(bytes.NewReader(append(ftyp, meta...))); err == nil {
+		t.Errorf("Expected an error for a HEIF file with a broken item info box")
+	}
+}
+
+func TestRegistryExtractHEIFFiles(t *testing.T) {
+	dir := t.TempDir()
+	for name, brand := range map[string]string{"photo.HEIC": "heic", "photo.heif": "mif1", "photo.avif": "avif"} {
+		filePath := filepath.Join(dir, name)
+		if err := os.WriteFile(filePath, testHEIF(t, brand, false), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		if _, err := DefaultRegistry.Extract(filePath); err != nil {
+			t.Errorf("Extract(%s) returned error: %v", name, err)
+		}
+	}
+
+	if got := sniffContentType(testHEIF(t, "heic", false)[:32]); got != "image/heic" {
+		t.Errorf("Expected image/heic from ftyp brand, got %s", got)
+	}
+	if got := sniffContentType(testHEIF(t, "avif", false)[:32]); got != "image/avif" {
+		t.Errorf("Expected image/avif from ftyp brand, got %s", got)
+	}
+}
@@ -0,0 +1,342 @@
+package organizer
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// --- Undo journal ---
+
+// JournalFolder is the hidden folder inside the library that holds the journals of past runs.
+const JournalFolder = ".picgroup"
+
+// Journal operations besides the plan actions.
+const (
+	JournalMkdir = "mkdir" // a folder was created
+	JournalKeep  = "keep"  // a file to be deleted or replaced was moved next to the journal instead
+)
+
+// JournalEntry records one change on disk. A journal is a file of entries, one JSON object per
+// line, appended as the run goes.
+type JournalEntry struct {
+	Op          string `json:"op"`                    // ActionCopy, ActionMove, ActionLink, ActionRemove, JournalMkdir or JournalKeep
+	Source      string `json:"source,omitempty"`      // the file copied, moved, linked to, removed or kept
+	Destination string `json:"destination,omitempty"` // the new file, the created folder, or a copy of a removed or kept file
+	Size        int64  `json:"size,omitempty"`        // of the new file, or of the removed one
+	ModTime     int64  `json:"mtime,omitempty"`       // of the new file, or of the removed one, in Unix nanoseconds
+}
+
+// defaultJournalPath returns a new journal file in the library for a run started now.
+func (o *Organizer) defaultJournalPath() string {
+	return path.Join(o.destRoot(), JournalFolder, "journal-"+time.Now().Format("20060102-150405.000000")+".jsonl")
+}
+
+// createJournal creates a new journal file. A journal is never appended to by another run: when
+// journalPath is taken, the first free "name_1.jsonl", "name_2.jsonl", ... is used instead.
+func createJournal(journalPath string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(journalPath), 0755); err != nil {
+		return nil, err
+	}
+	ext := filepath.Ext(journalPath)
+	stem := strings.TrimSuffix(journalPath, ext)
+	candidate := journalPath
+	for i := 1; ; i++ {
+		f, err := os.OpenFile(candidate, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if !os.IsExist(err) {
+			return f, err
+		}
+		candidate = fmt.Sprintf("%s_%d%s", stem, i, ext)
+	}
+}
+
+// startJournal records the changes on disk from now on in journalPath, or the first free name
+// after it; "" records nothing. The file is only created with the first change.
+func (o *Organizer) startJournal(journalPath string) {
+	o.journalMu.Lock()
+	defer o.journalMu.Unlock()
+	o.journalTo = journalPath
+}
+
+// closeJournal stops recording and returns the journal written, or "" when nothing changed.
+func (o *Organizer) closeJournal() string {
+	o.journalMu.Lock()
+	defer o.journalMu.Unlock()
+	o.journalTo = ""
+	if o.journal == nil {
+		return ""
+	}
+	journalPath := o.journal.Name()
+	if err := o.journal.Close(); err != nil {
+		log.Printf("Error closing journal: %v", err)
+	}
+	o.journal = nil
+	o.keptFiles = 0
+	return journalPath
+}
+
+// record appends an entry to the journal. Every entry is written straight to the file, so a
+// killed run still leaves a journal of what it changed.
+func (o *Organizer) record(entry JournalEntry) {
+	o.journalMu.Lock()
+	defer o.journalMu.Unlock()
+	if o.journalTo == "" {
+		return
+	}
+	if !o.openJournal() {
+		return
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("Error writing journal: %v", err)
+		return
+	}
+	if _, err := o.journal.Write(append(line, '\n')); err != nil {
+		log.Printf("Error writing journal: %v", err)
+	}
+}
+
+// openJournal creates the journal file on first use and reports whether the run is journaled.
+// The caller holds journalMu.
+func (o *Organizer) openJournal() bool {
+	if o.journalTo == "" {
+		return false
+	}
+	if o.journal == nil {
+		f, err := createJournal(o.journalTo)
+		if err != nil {
+			log.Printf("Error creating journal, the run cannot be undone: %v", err)
+			o.journalTo = ""
+			return false
+		}
+		o.journal = f
+	}
+	return true
+}
+
+// keptFolder returns the folder next to a journal that holds the files its run deleted or replaced.
+func keptFolder(journalPath string) string {
+	return strings.TrimSuffix(journalPath, filepath.Ext(journalPath)) + ".kept"
+}
+
+// keep moves a file that is about to be deleted or replaced into the kept folder of the journal,
+// so undo can bring it back. It returns false, changing nothing, when the run is not journaled.
+func (o *Organizer) keep(filePath string) (bool, error) {
+	o.journalMu.Lock()
+	if !o.openJournal() {
+		o.journalMu.Unlock()
+		return false, nil
+	}
+	o.keptFiles++
+	kept := filepath.Join(keptFolder(o.journal.Name()), fmt.Sprintf("%d-%s", o.keptFiles, filepath.Base(filePath)))
+	o.journalMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(kept), 0755); err != nil {
+		return false, err
+	}
+	if err := moveFile(filePath, kept); err != nil {
+		return false, err
+	}
+	o.recordFile(JournalKeep, filePath, kept, kept)
+	return true, nil
+}
+
+// recordFile records an operation together with the size and modification time of file, so an
+// undo can tell whether it changed since.
+func (o *Organizer) recordFile(op, source, destination, file string) {
+	entry := JournalEntry{Op: op, Source: source, Destination: destination}
+	if stat, err := os.Stat(file); err == nil {
+		entry.Size, entry.ModTime = stat.Size(), stat.ModTime().UnixNano()
+	}
+	o.record(entry)
+}
+
+// mkdirAll creates a folder and its missing parents and journals each folder it created. Folders
+// created for the checkpoint count as created here, as the run put them there.
+func (o *Organizer) mkdirAll(dir string, perm os.FileMode) error {
+	missing := missingDirs(dir, func(d string) bool { return dirMissing(d) || o.checkpointCreated(d) })
+	if err := os.MkdirAll(dir, perm); err != nil {
+		return err
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		o.record(JournalEntry{Op: JournalMkdir, Destination: missing[i]})
+	}
+	o.checkpointMu.Lock()
+	for _, d := range missing {
+		delete(o.checkpointDirs, d)
+	}
+	o.checkpointMu.Unlock()
+	return nil
+}
+
+// missingDirs returns dir and its parents for as long as missing reports them missing, deepest
+// first.
+func missingDirs(dir string, missing func(string) bool) []string {
+	var dirs []string
+	for d := dir; missing(d); d = filepath.Dir(d) {
+		dirs = append(dirs, d)
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	return dirs
+}
+
+// dirMissing reports whether a folder does not exist.
+func dirMissing(dir string) bool {
+	_, err := os.Stat(dir)
+	return os.IsNotExist(err)
+}
+
+// ReadJournal reads the entries of a journal. A last line cut off by a killed run is left out.
+func ReadJournal(journalPath string) ([]JournalEntry, error) {
+	f, err := os.Open(journalPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []JournalEntry
+	var broken error
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		if broken != nil {
+			return nil, broken
+		}
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			broken = fmt.Errorf("reading journal %s: line %d: %w", journalPath, n, err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading journal %s: %w", journalPath, err)
+	}
+	if broken != nil {
+		log.Printf("Ignoring the incomplete end of the journal: %v", broken)
+	}
+	return entries, nil
+}
+
+// unchanged checks that a file is still as the journal recorded it.
+func unchanged(entry JournalEntry, file string) error {
+	stat, err := os.Stat(file)
+	if err != nil {
+		return fmt.Errorf("%s is gone: %w", file, err)
+	}
+	if stat.Size() != entry.Size || stat.ModTime().UnixNano() != entry.ModTime {
+		return fmt.Errorf("%s changed since the run", file)
+	}
+	return nil
+}
+
+// Undo reverses the changes recorded in a journal, newest first: moved files go back to where
+// they were, copies and links are removed, deleted and replaced files are restored from the copy
+// kept of them and the created folders are removed when they are empty. Nothing is changed when
+// a file was modified, replaced or removed since the run, when an original path is taken again or
+// when a file cannot be restored.
+func (o *Organizer) Undo(journalPath string) error {
+	entries, err := ReadJournal(journalPath)
+	if err != nil {
+		return err
+	}
+
+	// Paths the run put files at; they are free again once those are undone.
+	placed := make(map[string]bool)
+	for _, entry := range entries {
+		switch entry.Op {
+		case ActionMove, ActionCopy, ActionLink:
+			placed[entry.Destination] = true
+		}
+	}
+
+	var problems []error
+	for _, entry := range entries {
+		switch entry.Op {
+		case ActionMove:
+			if err := unchanged(entry, entry.Destination); err != nil {
+				problems = append(problems, err)
+			} else if _, err := os.Lstat(entry.Source); err == nil {
+				problems = append(problems, fmt.Errorf("%s exists again", entry.Source))
+			}
+		case ActionCopy, ActionLink:
+			if err := unchanged(entry, entry.Destination); err != nil {
+				problems = append(problems, err)
+			}
+		case ActionRemove:
+			if entry.Destination == "" {
+				problems = append(problems, fmt.Errorf("%s was deleted and no copy was kept", entry.Source))
+			} else if stat, err := os.Stat(entry.Destination); err != nil || stat.Size() != entry.Size {
+				problems = append(problems, fmt.Errorf("the copy %s of %s is gone or changed", entry.Destination, entry.Source))
+			} else if _, err := os.Lstat(entry.Source); err == nil {
+				problems = append(problems, fmt.Errorf("%s exists again", entry.Source))
+			}
+		case JournalKeep:
+			if err := unchanged(entry, entry.Destination); err != nil {
+				problems = append(problems, err)
+			} else if _, err := os.Lstat(entry.Source); err == nil && !placed[entry.Source] {
+				problems = append(problems, fmt.Errorf("%s exists again", entry.Source))
+			}
+		case JournalMkdir:
+		default:
+			problems = append(problems, fmt.Errorf("unknown journal operation %q", entry.Op))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("refusing to undo %s: %w", journalPath, errors.Join(problems...))
+	}
+
+	var errs []error
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if o.VerboseMode == "1" {
+			fmt.Printf("undo %s %s %s\n", entry.Op, entry.Source, entry.Destination)
+		}
+		var err error
+		switch entry.Op {
+		case ActionMove:
+			_, err = o.move(entry.Destination, entry.Source)
+		case ActionCopy, ActionLink:
+			err = os.Remove(entry.Destination)
+		case ActionRemove:
+			err = restoreRemoved(entry)
+		case JournalKeep:
+			if _, statErr := os.Lstat(entry.Source); statErr == nil {
+				err = fmt.Errorf("%s exists", entry.Source)
+			} else {
+				_, err = o.move(entry.Destination, entry.Source)
+			}
+		case JournalMkdir:
+			// Folders that hold files the run did not put there stay.
+			if err := os.Remove(entry.Destination); err != nil && o.VerboseMode == "1" {
+				fmt.Printf("Keeping folder %s: %v\n", entry.Destination, err)
+			}
+		}
+		if err != nil {
+			log.Printf("Error undoing %s of %s: %v", entry.Op, entry.Source, err)
+			errs = append(errs, err)
+		}
+	}
+	os.Remove(keptFolder(journalPath))
+	return errors.Join(errs...)
+}
+
+// restoreRemoved copies a removed file back from the copy the library kept of it.
+func restoreRemoved(entry JournalEntry) error {
+	if err := copyVerified(entry.Destination, entry.Source); err != nil {
+		return err
+	}
+	modTime := time.Unix(0, entry.ModTime)
+	return os.Chtimes(entry.Source, modTime, modTime)
+}
@@ -0,0 +1,244 @@
+package organizer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUndoMove(t *testing.T) {
+	src, library := t.TempDir(), t.TempDir()
+	album := filepath.Join(src, "Album")
+	if err := os.MkdirAll(album, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFiles(t, album, "IMG_20230501_143000.xyz", "IMG_20230502_100000.xyz")
+	journalPath := filepath.Join(t.TempDir(), "journal.jsonl")
+
+	org := NewOrganizer(src, "{year}/{month:02}/{day:02}", "generated", "seq", "0", "move")
+	org.Extractors = testChainRegistry()
+	org.OutputRoot = filepath.Join(library, "Photos")
+	org.JournalPath = journalPath
+	org.Run(0)
+
+	entries, err := ReadJournal(journalPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	moves := 0
+	for _, entry := range entries {
+		if entry.Op == ActionMove {
+			moves++
+		}
+	}
+	if moves != 2 {
+		t.Fatalf("Expected 2 journaled moves, got %+v", entries)
+	}
+
+	if err := org.Undo(journalPath); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"IMG_20230501_143000.xyz", "IMG_20230502_100000.xyz"} {
+		if _, err := os.Stat(filepath.Join(album, name)); err != nil {
+			t.Errorf("Expected %s back in its album: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(library, "Photos")); !os.IsNotExist(err) {
+		t.Errorf("Expected the created folders to be removed, got %v", err)
+	}
+}
+
+func TestUndoKeepsFoldersInUse(t *testing.T) {
+	src := t.TempDir()
+	writeTestFiles(t, src, "IMG_20230501_143000.xyz")
+	journalPath := filepath.Join(t.TempDir(), "journal.jsonl")
+
+	org := NewOrganizer(src, "ymd", "generated", "seq", "0", "copy")
+	org.Extractors = testChainRegistry()
+	org.JournalPath = journalPath
+	org.Run(0)
+
+	day := filepath.Join(src, "generated", "20230501")
+	writeTestFiles(t, day, "notes.txt")
+	if err := org.Undo(journalPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(day, "IMG_20230501_143000.xyz")); !os.IsNotExist(err) {
+		t.Errorf("Expected the copy to be removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(day, "notes.txt")); err != nil {
+		t.Errorf("Expected the folder with a new file to stay: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(src, "IMG_20230501_143000.xyz")); err != nil {
+		t.Errorf("Expected the original to stay: %v", err)
+	}
+}
+
+func TestUndoRefusesChangedFiles(t *testing.T) {
+	src := t.TempDir()
+	writeTestFiles(t, src, "IMG_20230501_143000.xyz", "IMG_20230502_100000.xyz")
+	journalPath := filepath.Join(t.TempDir(), "journal.jsonl")
+
+	org := NewOrganizer(src, "ymd", "generated", "seq", "0", "move")
+	org.Extractors = testChainRegistry()
+	org.JournalPath = journalPath
+	org.Run(0)
+
+	edited := filepath.Join(src, "generated", "20230502", "IMG_20230502_100000.xyz")
+	if err := os.WriteFile(edited, []byte("edited since"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := org.Undo(journalPath); err == nil {
+		t.Fatal("Expected the undo to be refused")
+	}
+	// Nothing was undone, not even the unchanged file.
+	if _, err := os.Stat(filepath.Join(src, "generated", "20230501", "IMG_20230501_143000.xyz")); err != nil {
+		t.Errorf("Expected the unchanged file to stay: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(src, "IMG_20230501_143000.xyz")); !os.IsNotExist(err) {
+		t.Errorf("Expected no file to be restored, got %v", err)
+	}
+}
+
+func TestUndoRestoresLinkedDuplicate(t *testing.T) {
+	src, library := t.TempDir(), t.TempDir()
+	if err := os.MkdirAll(filepath.Join(library, "20230501"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFiles(t, filepath.Join(library, "20230501"), "IMG_20230501_143000.xyz")
+	writeTestFiles(t, src, "IMG_20230502_100000.xyz")
+	journalPath := filepath.Join(t.TempDir(), "journal.jsonl")
+
+	org := NewOrganizer(src, "ymd", "generated", "seq", "0", "move")
+	org.Extractors = testChainRegistry()
+	org.OutputRoot = library
+	org.DedupMode = DedupLink
+	org.JournalPath = journalPath
+	org.Run(0)
+
+	duplicate := filepath.Join(src, "IMG_20230502_100000.xyz")
+	if _, err := os.Stat(duplicate); !os.IsNotExist(err) {
+		t.Fatalf("Expected the duplicate to be removed, got %v", err)
+	}
+	if err := org.Undo(journalPath); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(duplicate); string(got) != "custom" {
+		t.Errorf("Expected the duplicate to be restored, got %q (%v)", got, err)
+	}
+	if _, err := os.Lstat(filepath.Join(library, "20230502", "IMG_20230502_100000.xyz")); !os.IsNotExist(err) {
+		t.Errorf("Expected the link to be removed, got %v", err)
+	}
+}
+
+func TestJournalPerRun(t *testing.T) {
+	src := t.TempDir()
+	writeTestFiles(t, src, "IMG_20230501_143000.xyz", "IMG_20230502_100000.xyz")
+	journalPath := filepath.Join(t.TempDir(), "journal.jsonl")
+
+	org := NewOrganizer(src, "ymd", "generated", "seq", "0", "copy")
+	org.Extractors = testChainRegistry()
+	org.JournalPath = journalPath
+	org.Run(0)
+	os.RemoveAll(filepath.Join(src, "generated"))
+	org.Run(0)
+
+	for _, name := range []string{"journal.jsonl", "journal_1.jsonl"} {
+		entries, err := ReadJournal(filepath.Join(filepath.Dir(journalPath), name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		copies := 0
+		for _, entry := range entries {
+			if entry.Op == ActionCopy {
+				copies++
+			}
+		}
+		if copies != 2 {
+			t.Errorf("Expected %s to hold the 2 copies of one run, got %+v", name, entries)
+		}
+	}
+}
+
+func TestUndoDroppedSidecar(t *testing.T) {
+	src := t.TempDir()
+	writeTestFiles(t, src, "IMG_0001.xyz", "IMG_0001.xyz.json")
+	journalPath := filepath.Join(t.TempDir(), "journal.jsonl")
+
+	org := NewOrganizer(src, "ymd", "generated", "seq", "0", "move")
+	org.Extractors = testChainRegistry()
+	org.SidecarMode = SidecarDrop
+	org.JournalPath = journalPath
+	org.Run(0)
+
+	sidecar := filepath.Join(src, "IMG_0001.xyz.json")
+	if _, err := os.Stat(sidecar); !os.IsNotExist(err) {
+		t.Fatalf("Expected the sidecar to be dropped, got %v", err)
+	}
+	if err := org.Undo(journalPath); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(sidecar); string(got) != testTakeoutJSON {
+		t.Errorf("Expected the sidecar to be restored, got %q (%v)", got, err)
+	}
+	if _, err := os.Stat(keptFolder(journalPath)); !os.IsNotExist(err) {
+		t.Errorf("Expected the kept folder to be removed, got %v", err)
+	}
+}
+
+func TestUndoRefusesUnrecoverableRemoval(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, "copy.xyz")
+	copied := filepath.Join(dir, "copy.xyz")
+	stat, err := os.Stat(copied)
+	if err != nil {
+		t.Fatal(err)
+	}
+	journalPath := filepath.Join(dir, "journal.jsonl")
+	org := NewOrganizer(dir, "ymd", "generated", "seq", "0", "move")
+	org.startJournal(journalPath)
+	org.record(JournalEntry{Op: ActionCopy, Source: filepath.Join(dir, "original.xyz"), Destination: copied, Size: stat.Size(), ModTime: stat.ModTime().UnixNano()})
+	org.record(JournalEntry{Op: ActionRemove, Source: filepath.Join(dir, "gone.xyz")})
+	org.closeJournal()
+
+	if err := org.Undo(journalPath); err == nil {
+		t.Fatal("Expected the undo to be refused")
+	}
+	if _, err := os.Stat(copied); err != nil {
+		t.Errorf("Expected nothing to be undone: %v", err)
+	}
+}
+
+func TestUndoOverwrite(t *testing.T) {
+	src, library := t.TempDir(), t.TempDir()
+	day := filepath.Join(library, "20230501")
+	if err := os.MkdirAll(day, 0755); err != nil {
+		t.Fatal(err)
+	}
+	existing := filepath.Join(day, "IMG_20230501_143000.xyz")
+	if err := os.WriteFile(existing, []byte("original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFiles(t, src, "IMG_20230501_143000.xyz")
+	journalPath := filepath.Join(t.TempDir(), "journal.jsonl")
+
+	org := NewOrganizer(src, "ymd", "generated", "seq", "0", "move")
+	org.Extractors = testChainRegistry()
+	org.OutputRoot = library
+	org.CollisionPolicy = CollisionOverwrite
+	org.JournalPath = journalPath
+	org.Run(0)
+
+	if got, _ := os.ReadFile(existing); string(got) != "custom" {
+		t.Fatalf("Expected the library file to be overwritten, got %q", got)
+	}
+	if err := org.Undo(journalPath); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(existing); string(got) != "original" {
+		t.Errorf("Expected the original bytes back in the library, got %q (%v)", got, err)
+	}
+	if got, err := os.ReadFile(filepath.Join(src, "IMG_20230501_143000.xyz")); string(got) != "custom" {
+		t.Errorf("Expected the new file back in the source, got %q (%v)", got, err)
+	}
+}
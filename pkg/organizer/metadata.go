@@ -0,0 +1,489 @@
+package organizer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dsoprea/go-exif/v3"
+	exifcommon "github.com/dsoprea/go-exif/v3/common"
+)
+
+// --- Errors returned by the metadata layer ---
+
+var (
+	// ErrUnsupportedMedia is returned when no extractor is registered for a file's content type.
+	ErrUnsupportedMedia = errors.New("unsupported media type")
+
+	// ErrNoCaptureDate is returned when a file is supported but carries no usable capture date.
+	ErrNoCaptureDate = errors.New("no capture date found")
+)
+
+// DateSource identifies where a MediaInfo capture time came from.
+type DateSource string
+
+// Date sources recognised by the built-in extractors.
+const (
+	DateSourceExifOriginal  DateSource = "exif:DateTimeOriginal"
+	DateSourceExifDigitized DateSource = "exif:CreateDate"
+	DateSourceExifDateTime  DateSource = "exif:DateTime"
+)
+
+// GPSInfo holds the position a media file was captured at, in decimal degrees.
+type GPSInfo struct {
+	Latitude  float64
+	Longitude float64
+	Altitude  float64
+}
+
+// DateCandidate is one capture date found in a file.
+type DateCandidate struct {
+	Time     time.Time
+	Timezone *time.Location
+	Instant  bool // see MediaInfo.Instant
+	Source   DateSource
+}
+
+// MediaInfo holds the metadata the organizer needs from a media file.
+type MediaInfo struct {
+	// CaptureTime is when the media was captured. Timezone is the zone of the
+	// capture location when the file records it (OffsetTimeOriginal, a GPS fix or a
+	// video's creationdate). When Timezone is nil and Instant is set, CaptureTime is
+	// an exact instant shown in local time (video headers, "Z" times); otherwise it
+	// holds the camera's wall clock.
+	CaptureTime time.Time
+	Timezone    *time.Location
+	Instant     bool
+	DateSource  DateSource
+
+	// Dates lists every capture date found in the file, best first, so the
+	// organizer's date chain can pick a specific source.
+	Dates []DateCandidate
+
+	Make   string
+	Model  string
+	Serial string // camera body serial number
+	Lens   string
+	Width  int
+	Height int
+	GPS    *GPSInfo
+
+	// ContentType is the type the registry resolved the file to, such as "image/jpeg".
+	ContentType string
+
+	// Sidecars lists the metadata files that belong to the media and travel with it.
+	Sidecars []string
+
+	// Correction is set when a clock rule moved CaptureTime.
+	Correction *ClockCorrection
+}
+
+// MetadataExtractor reads capture metadata from the content of a media file.
+type MetadataExtractor interface {
+	Extract(r io.ReadSeeker) (*MediaInfo, error)
+}
+
+// ExtractorFunc adapts an ordinary function to the MetadataExtractor interface.
+type ExtractorFunc func(r io.ReadSeeker) (*MediaInfo, error)
+
+// Extract calls f(r).
+func (f ExtractorFunc) Extract(r io.ReadSeeker) (*MediaInfo, error) {
+	return f(r)
+}
+
+// --- Extractor registry ---
+
+// Registry maps content types to the extractors able to read them.
+type Registry struct {
+	mu         sync.RWMutex
+	extractors map[string][]MetadataExtractor
+	extensions map[string]string
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		extractors: make(map[string][]MetadataExtractor),
+		extensions: make(map[string]string),
+	}
+}
+
+// DefaultRegistry holds the built-in extractors and is used by organizers without their own registry.
+var DefaultRegistry = newDefaultRegistry()
+
+// RegisterExtractor adds an extractor to the DefaultRegistry.
+func RegisterExtractor(contentType string, e MetadataExtractor, extensions ...string) {
+	DefaultRegistry.Register(contentType, e, extensions...)
+}
+
+func newDefaultRegistry() *Registry {
+	r := NewRegistry()
+	search := ExtractorFunc(extractExifSearch)
+	r.Register("image/jpeg", search, ".jpg", ".jpeg")
+	r.Register("image/png", ExtractorFunc(extractPNG), ".png")
+	r.Register("image/webp", ExtractorFunc(extractWebP), ".webp")
+	r.Register("image/gif", ExtractorFunc(extractGIF), ".gif")
+
+	tiff := ExtractorFunc(extractTIFF)
+	r.Register("image/tiff", tiff, ".tif", ".tiff")
+	for _, format := range rawFormats {
+		r.Register(format.contentType, tiff, format.extensions...)
+	}
+	r.Register("image/x-fuji-raf", ExtractorFunc(extractRAF), ".raf")
+	r.Register("image/x-canon-cr3", ExtractorFunc(extractCR3), ".cr3")
+
+	quickTime := ExtractorFunc(extractQuickTime)
+	r.Register("video/mp4", quickTime, ".mp4")
+	r.Register("video/quicktime", quickTime, ".mov", ".qt")
+	r.Register("video/x-m4v", quickTime, ".m4v")
+	r.Register("video/3gpp", quickTime, ".3gp")
+	r.Register("video/3gpp2", quickTime, ".3g2")
+
+	heif := ExtractorFunc(extractHEIF)
+	r.Register("image/heic", heif, ".heic")
+	r.Register("image/heif", heif, ".heif", ".hif")
+	r.Register("image/avif", heif, ".avif")
+	return r
+}
+
+// Register adds an extractor for contentType and maps the given file extensions to it.
+// Extractors registered later for the same content type are tried first, so callers
+// can override the built-in ones and still fall back to them.
+func (r *Registry) Register(contentType string, e MetadataExtractor, extensions ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.extractors[contentType] = append([]MetadataExtractor{e}, r.extractors[contentType]...)
+	for _, ext := range extensions {
+		r.extensions[normalizeExt(ext)] = contentType
+	}
+}
+
+// ContentType returns the content type for a file, using its extension first
+// and falling back to sniffing the header bytes.
+func (r *Registry) ContentType(filePath string, header []byte) string {
+	r.mu.RLock()
+	contentType, ok := r.extensions[normalizeExt(filepath.Ext(filePath))]
+	r.mu.RUnlock()
+	if ok {
+		return contentType
+	}
+	return sniffContentType(header)
+}
+
+// Extractors returns the extractors registered for contentType, in the order they are tried.
+func (r *Registry) Extractors(contentType string) []MetadataExtractor {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]MetadataExtractor(nil), r.extractors[contentType]...)
+}
+
+// Extract opens filePath and returns the metadata of the first extractor that finds a capture date.
+// When none finds a date, whatever metadata was read is returned together with the error;
+// ErrUnsupportedMedia means no extractor handles the file at all.
+func (r *Registry) Extract(filePath string) (*MediaInfo, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	header := make([]byte, 32)
+	n, _ := io.ReadFull(f, header)
+	header = header[:n]
+
+	contentType := r.ContentType(filePath, header)
+	extractors := r.Extractors(contentType)
+	if len(extractors) == 0 {
+		return nil, ErrUnsupportedMedia
+	}
+
+	var partial *MediaInfo
+	lastErr := ErrNoCaptureDate
+	for _, e := range extractors {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		info, err := e.Extract(f)
+		if err == nil && info != nil && !info.CaptureTime.IsZero() {
+			info.ContentType = contentType
+			if len(info.Dates) == 0 {
+				info.Dates = []DateCandidate{{Time: info.CaptureTime, Timezone: info.Timezone, Instant: info.Instant, Source: info.DateSource}}
+			}
+			return info, nil
+		}
+		if err != nil {
+			lastErr = err
+		}
+		if partial == nil && info != nil {
+			partial = info
+		}
+	}
+	if partial == nil {
+		partial = &MediaInfo{}
+	}
+	partial.ContentType = contentType
+	return partial, fmt.Errorf("%s: %w", contentType, lastErr)
+}
+
+// normalizeExt lowercases an extension and makes sure it starts with a dot.
+func normalizeExt(ext string) string {
+	ext = strings.ToLower(ext)
+	if ext != "" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+// sniffContentType detects a content type from the leading bytes of a file.
+func sniffContentType(header []byte) string {
+	switch {
+	case bytes.HasPrefix(header, []byte{0xFF, 0xD8, 0xFF}):
+		return "image/jpeg"
+	case bytes.HasPrefix(header, []byte(pngSignature)):
+		return "image/png"
+	case len(header) >= 12 && string(header[0:4]) == "RIFF" && string(header[8:12]) == "WEBP":
+		return "image/webp"
+	case bytes.HasPrefix(header, []byte("GIF87a")) || bytes.HasPrefix(header, []byte("GIF89a")):
+		return "image/gif"
+	case bytes.HasPrefix(header, []byte("II*\x00")) || bytes.HasPrefix(header, []byte("MM\x00*")):
+		return "image/tiff"
+	case bytes.HasPrefix(header, []byte(rafMagic)):
+		return "image/x-fuji-raf"
+	case len(header) >= 12 && string(header[4:8]) == "ftyp":
+		return sniffFtyp(header)
+	case len(header) >= 8 && isQuickTimeAtom(string(header[4:8])):
+		// Old QuickTime movies have no ftyp box and start straight with an atom.
+		return "video/quicktime"
+	default:
+		return ""
+	}
+}
+
+// sniffFtyp detects the content type of an ISO-BMFF file from the brands in its leading ftyp box.
+func sniffFtyp(header []byte) string {
+	end := int(binary.BigEndian.Uint32(header[0:4]))
+	if end > len(header) || end < 8 {
+		end = len(header)
+	}
+	brands := ftypBrands(header[8:end])
+	for _, brand := range brands {
+		switch {
+		case brand == "heic" || brand == "heix" || brand == "heim" || brand == "heis" || brand == "hevc" || brand == "hevx":
+			return "image/heic"
+		case brand == "avif" || brand == "avis":
+			return "image/avif"
+		case brand == "mif1" || brand == "msf1":
+			return "image/heif"
+		case brand == "crx ":
+			return "image/x-canon-cr3"
+		case brand == "qt  ":
+			return "video/quicktime"
+		case brand == "M4V " || brand == "M4VH" || brand == "M4VP":
+			return "video/x-m4v"
+		case strings.HasPrefix(brand, "3g2"):
+			return "video/3gpp2"
+		case strings.HasPrefix(brand, "3gp"):
+			return "video/3gpp"
+		}
+	}
+	return "video/mp4"
+}
+
+// isQuickTimeAtom reports whether a top-level atom type is typical for a QuickTime movie.
+func isQuickTimeAtom(atom string) bool {
+	switch atom {
+	case "moov", "mdat", "wide", "free", "skip", "pnot":
+		return true
+	}
+	return false
+}
+
+// dateSourceRank orders date sources the way EXIF does: original capture first,
+// then digitization/creation, then modification and container times.
+func dateSourceRank(source DateSource) int {
+	switch source {
+	case DateSourceExifOriginal, DateSourceXMPOriginal, DateSourceSidecarOriginal:
+		return 0
+	case DateSourceExifDigitized, DateSourceXMPCreateDate, DateSourceXMPDateCreated,
+		DateSourceSidecarCreateDate, DateSourceSidecarDateCreated:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// setDates stores the candidates ranked best first and takes the capture time from the best one.
+// It returns ErrNoCaptureDate when there is no candidate.
+func (info *MediaInfo) setDates(candidates []DateCandidate) error {
+	info.Dates = info.Dates[:0]
+	for _, c := range candidates {
+		if !c.Time.IsZero() {
+			info.Dates = append(info.Dates, c)
+		}
+	}
+	sort.SliceStable(info.Dates, func(i, j int) bool {
+		return dateSourceRank(info.Dates[i].Source) < dateSourceRank(info.Dates[j].Source)
+	})
+
+	if len(info.Dates) == 0 {
+		return ErrNoCaptureDate
+	}
+	best := info.Dates[0]
+	info.CaptureTime, info.Timezone, info.Instant, info.DateSource = best.Time, best.Timezone, best.Instant, best.Source
+	return nil
+}
+
+// --- EXIF helpers shared by the built-in extractors ---
+
+// exifTimeLayouts lists the date formats seen in the wild for EXIF date tags.
+var exifTimeLayouts = []string{
+	"2006:01:02 15:04:05",
+	"2006-01-02 15:04:05",
+	"2006:01:02T15:04:05",
+	"2006-01-02T15:04:05",
+}
+
+// parseExifTime parses an EXIF date string, ignoring padding and the all-zero placeholder.
+func parseExifTime(s string) (time.Time, bool) {
+	s = strings.TrimSpace(strings.TrimRight(s, "\x00"))
+	if s == "" || strings.HasPrefix(s, "0000") {
+		return time.Time{}, false
+	}
+	for _, layout := range exifTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// extractExifSearch scans the content for an EXIF block and reads it.
+func extractExifSearch(r io.ReadSeeker) (*MediaInfo, error) {
+	rawExif, err := exif.SearchAndExtractExifWithReader(r)
+	if err != nil {
+		return nil, ErrNoCaptureDate
+	}
+	return mediaInfoFromExif(rawExif)
+}
+
+// mediaInfoFromExif flattens a raw EXIF block (starting at the TIFF header) into a MediaInfo.
+func mediaInfoFromExif(rawExif []byte) (*MediaInfo, error) {
+	flatExif, _, err := exif.GetFlatExifData(rawExif, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]exif.ExifTag, len(flatExif))
+	for _, item := range flatExif {
+		// The first occurrence wins so IFD0 is preferred over the thumbnail IFD.
+		if _, ok := tags[item.TagName]; !ok {
+			tags[item.TagName] = item
+		}
+	}
+
+	info := &MediaInfo{
+		Make:  strings.TrimSpace(tags["Make"].FormattedFirst),
+		Model: strings.TrimSpace(tags["Model"].FormattedFirst),
+	}
+	info.Lens = strings.TrimSpace(tags["LensModel"].FormattedFirst)
+	for _, tag := range []string{"BodySerialNumber", "CameraSerialNumber"} {
+		if serial := strings.TrimSpace(tags[tag].FormattedFirst); serial != "" && info.Serial == "" {
+			info.Serial = serial
+		}
+	}
+
+	var candidates []DateCandidate
+	for _, date := range []struct {
+		tag, subSec, offset string
+		source              DateSource
+	}{
+		{"DateTimeOriginal", "SubSecTimeOriginal", "OffsetTimeOriginal", DateSourceExifOriginal},
+		{"DateTimeDigitized", "SubSecTimeDigitized", "OffsetTimeDigitized", DateSourceExifDigitized},
+		{"DateTime", "SubSecTime", "OffsetTime", DateSourceExifDateTime},
+	} {
+		candidate, ok := exifDateCandidate(tags[date.tag].FormattedFirst, tags[date.subSec].FormattedFirst, tags[date.offset].FormattedFirst, date.source)
+		if ok {
+			candidates = append(candidates, candidate)
+		}
+	}
+	if hours, ok := exifDegrees(tags["GPSTimeStamp"].Value); ok {
+		if gpsTime, ok := gpsDateTime(tags["GPSDateStamp"].FormattedFirst, hours); ok {
+			candidates = applyGPSTime(candidates, gpsTime)
+		}
+	}
+
+	info.Width = exifInt(tags["PixelXDimension"].Value)
+	if info.Width == 0 {
+		info.Width = exifInt(tags["ImageWidth"].Value)
+	}
+	info.Height = exifInt(tags["PixelYDimension"].Value)
+	if info.Height == 0 {
+		info.Height = exifInt(tags["ImageLength"].Value)
+	}
+
+	lat, latOK := exifDegrees(tags["GPSLatitude"].Value)
+	lon, lonOK := exifDegrees(tags["GPSLongitude"].Value)
+	if latOK && lonOK {
+		if tags["GPSLatitudeRef"].FormattedFirst == "S" {
+			lat = -lat
+		}
+		if tags["GPSLongitudeRef"].FormattedFirst == "W" {
+			lon = -lon
+		}
+		info.GPS = &GPSInfo{Latitude: lat, Longitude: lon}
+		if alt, ok := exifRational(tags["GPSAltitude"].Value); ok {
+			info.GPS.Altitude = alt
+		}
+	}
+
+	return info, info.setDates(candidates)
+}
+
+// exifInt returns the first value of an integer EXIF tag.
+func exifInt(value interface{}) int {
+	switch v := value.(type) {
+	case []uint16:
+		if len(v) > 0 {
+			return int(v[0])
+		}
+	case []uint32:
+		if len(v) > 0 {
+			return int(v[0])
+		}
+	}
+	return 0
+}
+
+// exifRational returns the first value of a rational EXIF tag.
+func exifRational(value interface{}) (float64, bool) {
+	v, ok := value.([]exifcommon.Rational)
+	if !ok || len(v) == 0 || v[0].Denominator == 0 {
+		return 0, false
+	}
+	return float64(v[0].Numerator) / float64(v[0].Denominator), true
+}
+
+// exifDegrees converts a degrees/minutes/seconds rational triple to decimal degrees.
+func exifDegrees(value interface{}) (float64, bool) {
+	v, ok := value.([]exifcommon.Rational)
+	if !ok || len(v) != 3 {
+		return 0, false
+	}
+	var parts [3]float64
+	for i, r := range v {
+		if r.Denominator == 0 {
+			return 0, false
+		}
+		parts[i] = float64(r.Numerator) / float64(r.Denominator)
+	}
+	return parts[0] + parts[1]/60 + parts[2]/3600, true
+}
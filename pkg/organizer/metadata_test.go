@@ -0,0 +1,95 @@
+package organizer
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRegistryExtractSampleJPEG(t *testing.T) {
+	info, err := DefaultRegistry.Extract(filepath.Join(testImagesDir, "generated_sample_001.JPG"))
+	if err != nil {
+		t.Fatalf("Extract returned error: %v", err)
+	}
+	if info.DateSource != DateSourceExifOriginal {
+		t.Errorf("Expected date source %s, got %s", DateSourceExifOriginal, info.DateSource)
+	}
+	if info.CaptureTime.IsZero() {
+		t.Errorf("Expected a capture time, got zero")
+	}
+}
+
+func TestRegistryCustomExtractor(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "clip.xyz")
+	if err := os.WriteFile(filePath, []byte("custom"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	want := time.Date(2023, 5, 1, 14, 30, 0, 0, time.UTC)
+	registry := NewRegistry()
+	registry.Register("application/x-test", ExtractorFunc(func(r io.ReadSeeker) (*MediaInfo, error) {
+		return nil, ErrNoCaptureDate
+	}), ".xyz")
+	registry.Register("application/x-test", ExtractorFunc(func(r io.ReadSeeker) (*MediaInfo, error) {
+		data, err := io.ReadAll(r)
+		if err != nil || string(data) != "custom" {
+			t.Errorf("Expected extractor to read file content from the start, got %q (%v)", data, err)
+		}
+		return &MediaInfo{CaptureTime: want, DateSource: "test"}, nil
+	}))
+
+	org := NewOrganizer(dir, "ymd", "generated", "seq", "0", "copy")
+	org.Extractors = registry
+	info, err := org.readMediaInfo(filePath)
+	if err != nil {
+		t.Fatalf("readMediaInfo returned error: %v", err)
+	}
+	if !info.CaptureTime.Equal(want) {
+		t.Errorf("Expected capture time %s, got %s", want, info.CaptureTime)
+	}
+}
+
+func TestRegistryUnsupported(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(filePath, []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	_, err := NewRegistry().Extract(filePath)
+	if !errors.Is(err, ErrUnsupportedMedia) {
+		t.Errorf("Expected ErrUnsupportedMedia, got %v", err)
+	}
+}
+
+func TestRegistryContentTypeSniffing(t *testing.T) {
+	registry := newDefaultRegistry()
+	if got := registry.ContentType("photo.JPG", nil); got != "image/jpeg" {
+		t.Errorf("Expected image/jpeg by extension, got %s", got)
+	}
+	if got := registry.ContentType("photo.bin", []byte{0xFF, 0xD8, 0xFF, 0xE1}); got != "image/jpeg" {
+		t.Errorf("Expected image/jpeg by header, got %s", got)
+	}
+	if got := registry.ContentType("photo.bin", []byte("nothing")); got != "" {
+		t.Errorf("Expected no content type, got %s", got)
+	}
+}
+
+func TestParseExifTime(t *testing.T) {
+	cases := map[string]bool{
+		"2023:05:01 14:30:00":     true,
+		"2023-05-01 14:30:00":     true,
+		"2023:05:01 14:30:00\x00": true,
+		"0000:00:00 00:00:00":     false,
+		"":                        false,
+	}
+	for input, ok := range cases {
+		if _, got := parseExifTime(input); got != ok {
+			t.Errorf("parseExifTime(%q) ok = %v, want %v", input, got, ok)
+		}
+	}
+}
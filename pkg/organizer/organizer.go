@@ -1,6 +1,7 @@
 package organizer
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -8,67 +9,23 @@ import (
 	"path"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/dsoprea/go-exif/v3"
-	"github.com/tidwall/gjson"
-
 	"runtime/debug"
 )
 
-// --- Overridable EXIF reader function (used for testing) ---
-
-var readMediaInfoFunc = defaultReadMediaInfo
-
-func defaultReadMediaInfo(filePath string) string {
-	ext := strings.ToUpper(filepath.Ext(filePath))
-	switch ext {
-	case ".JPG", ".JPEG", ".PNG", ".ARW":
-		rawExif, err := exif.SearchFileAndExtractExif(filePath)
-		if err != nil {
-			return ""
-		}
-		flatExif, _, err := exif.GetFlatExifData(rawExif, nil)
-		if err != nil {
-			return ""
-		}
-		
-		// Only extract the date we need, don't store all metadata
-		var dateTimeOriginal string
-		for _, item := range flatExif {
-			if item.TagName == "DateTimeOriginal" {
-				dateTimeOriginal = item.FormattedFirst
-				break
-			}
-		}
-		// Fallback to DateTime if DateTimeOriginal is not available
-		if dateTimeOriginal == "" {
-			for _, item := range flatExif {
-				if item.TagName == "DateTime" {
-					dateTimeOriginal = item.FormattedFirst
-					break
-				}
-			}
-		}
-		
-		if dateTimeOriginal != "" {
-			// Return minimal JSON with just the date
-			return fmt.Sprintf(`{"DateTimeOriginal":"%s"}`, dateTimeOriginal)
-		}
-		return ""
-	default:
-		return ""
-	}
-}
-
 // --- Core types and constructor ---
 
 // FileData holds minimal information about a file entry to be organized.
 type FileData struct {
-	Path    string
-	NewPath string
+	Path       string
+	NewPath    string
+	DateSource DateSource // where the capture date that picked NewPath came from
+	Sidecars   []string   // metadata files grouped together with the media
 }
 
 // Organizer holds configuration and state for organizing files.
@@ -80,27 +37,120 @@ type Organizer struct {
 	VerboseMode  string
 	GroupMode    string
 
+	// OutputRoot is the folder the organized library is built in. When empty, it is the Generated
+	// folder inside SrcPath.
+	OutputRoot string
+	// Sources are further folders organized in the same run as SrcPath, into the same library.
+	Sources []string
+	// Extractors reads capture metadata from media files. DefaultRegistry is used when nil.
+	Extractors *Registry
+	// DateChain lists the date sources tried in order, see DefaultDateChain (used when empty).
+	DateChain []string
+	// FilenamePatterns are tried before BuiltinFilenamePatterns by the filename date source.
+	FilenamePatterns []FilenamePattern
+	// SidecarMode is SidecarMove (default) or SidecarDrop.
+	SidecarMode string
+	// XMPPrecedence decides whether XMP sidecar dates (XMPPrecedenceSidecar, default) or
+	// embedded dates (XMPPrecedenceEmbedded) win.
+	XMPPrecedence string
+	// BucketLocation buckets files by the capture instant in this zone. When nil, files are
+	// bucketed by the local time at the shooting location.
+	BucketLocation *time.Location
+	// ClockRules correct the capture times of cameras with a wrong clock, see ParseClockTable.
+	ClockRules []*ClockRule
+	// Language names the months and weekdays in folder templates, see Languages. Defaults to English.
+	Language string
+	// RenameFormat is a template for the new file names, see ParseRenameFormat. Files keep their
+	// names when empty.
+	RenameFormat string
+	// CollisionPolicy decides what happens when a destination file exists, see ParseCollisionPolicy.
+	CollisionPolicy string
+	// DedupMode decides what happens to files whose content is already in the generated folder,
+	// see ParseDedupMode. Duplicates are not looked for when empty.
+	DedupMode string
+	// PerceptualHash groups near-duplicate photos with dHash or pHash, see ParsePerceptualHash, and
+	// routes all but the best copy of each group to ReviewFolder. Off when empty.
+	PerceptualHash string
+	// SimilarityThreshold is the number of hash bits near-duplicates may differ in; 0 only groups
+	// photos that hash alike. NewOrganizer sets DefaultSimilarityThreshold.
+	SimilarityThreshold int
+	// DryRun makes no changes on disk; the actions a run would take are collected as a plan
+	// instead, see Plan and WritePlan.
+	DryRun bool
+	// JournalPath is where Run journals its changes on disk for Undo; when empty, a new file in
+	// JournalFolder inside the library. ApplyPlan only journals when it is set.
+	JournalPath string
+	// Resume continues an interrupted run from the checkpoint in the library, skipping the folders
+	// and files it finished.
+	Resume bool
+	// PlanPath is where Run writes the plan of a dry run, as JSON or, for a ".csv" file, CSV.
+	PlanPath string
+
 	fEntries    []FileData
 	dateFolders map[string]bool
+	dateSources map[DateSource]int
+	corrections []CorrectedFile
+	folderTmpl  *Template
+	renameTmpl  *Template
+	pending     []pendingRename
+	sequences   map[string]pendingRename
+	collisionMu sync.Mutex
+	claimed     map[string]string        // destination -> file of this run that goes there
+	arriving    map[string]chan struct{} // claimed destinations, closed once the file arrived or was given up
+	collisions  []Collision
+	dedupMu     sync.Mutex
+	dedupIdx    *dedupIndex
+	duplicates  []Duplicate
+
+	similarImages []similarImage
+	similarBest   map[string]string // near-duplicate -> best copy of its group
+	similarGroups []SimilarGroup
+
+	statsMu     sync.Mutex
+	sourceStats map[string]*SourceSummary
+	renames     []RenamedFile
+
+	planMu   sync.Mutex
+	plan     []PlanAction
+	sidecars map[string]bool // sidecars already grouped with one of their media files
+
+	journalMu sync.Mutex
+	journalTo string
+	journal   *os.File
+	keptFiles int
+
+	checkpointMu   sync.Mutex
+	checkpoint     *os.File
+	checkpointDirs map[string]bool // folders created for the checkpoint and not journaled
+	finishedDirs   map[string]bool // folders a resumed run already organized
+	finishedFiles  map[string]bool
+	failures       atomic.Int64
 }
 
 // NewOrganizer creates a new Organizer instance with the given parameters.
 func NewOrganizer(srcPath, folderFormat, generated, copyMode, verboseMode, groupMode string) *Organizer {
 	return &Organizer{
-		SrcPath:      srcPath,
-		FolderFormat: folderFormat,
-		Generated:    generated,
-		CopyMode:     copyMode,
-		VerboseMode:  verboseMode,
-		GroupMode:    groupMode,
-		fEntries:     make([]FileData, 0),
-		dateFolders:  make(map[string]bool),
+		SrcPath:             srcPath,
+		FolderFormat:        folderFormat,
+		Generated:           generated,
+		CopyMode:            copyMode,
+		VerboseMode:         verboseMode,
+		GroupMode:           groupMode,
+		SimilarityThreshold: DefaultSimilarityThreshold,
+		fEntries:            make([]FileData, 0),
+		dateFolders:         make(map[string]bool),
+		dateSources:         make(map[DateSource]int),
 	}
 }
 
 // Execute creates an Organizer with the provided parameters and runs the organization process.
 func Execute(srcPath, folderFormat, generated, copyMode, verboseMode, groupMode string, workerCount int) {
-	if srcPath == "" {
+	NewOrganizer(srcPath, folderFormat, generated, copyMode, verboseMode, groupMode).Run(workerCount)
+}
+
+// Run scans the source path, creates the date folders and groups the files into them.
+func (o *Organizer) Run(workerCount int) {
+	if o.SrcPath == "" {
 		fmt.Println("Please define a valid path")
 		os.Exit(0)
 	}
@@ -112,40 +162,111 @@ func Execute(srcPath, folderFormat, generated, copyMode, verboseMode, groupMode
 	debug.SetGCPercent(20) // More aggressive GC
 	defer debug.SetGCPercent(100)
 
-	org := NewOrganizer(srcPath, folderFormat, generated, copyMode, verboseMode, groupMode)
-
-	if org.VerboseMode == "1" {
+	if o.VerboseMode == "1" {
 		defer trackTime(time.Now(), "process")
 	}
 
-	// First pass: scan and create folders
-	org.ScanAndCreateFolders(org.SrcPath)
-	
+	if !o.DryRun {
+		journalPath := o.JournalPath
+		if journalPath == "" {
+			journalPath = o.defaultJournalPath()
+		}
+		o.startJournal(journalPath)
+		o.startCheckpoint()
+	}
+
+	// First pass: scan all sources and create folders
+	sources := o.sources()
+	for _, src := range sources {
+		o.scanForDateFolders(src)
+	}
+	o.createFolders()
+
 	// Second pass: process files in streaming batches
-	org.ProcessFilesInBatches(org.SrcPath, workerCount)
+	for _, src := range sources {
+		o.ProcessFilesInBatches(src, workerCount)
+	}
+
+	if o.VerboseMode == "1" {
+		o.printDateSources()
+	}
+	o.printCorrections()
+	o.printRenames()
+	o.printCollisions()
+	o.printDuplicates()
+	o.printSimilarGroups()
+	o.printSourceSummaries()
+	if o.DryRun && o.PlanPath != "" {
+		if err := WritePlan(o.PlanPath, o.plan); err != nil {
+			log.Printf("Error writing plan: %v", err)
+		} else {
+			fmt.Printf("Wrote plan of %d actions to %s\n", len(o.plan), o.PlanPath)
+		}
+	}
+	o.closeCheckpoint(true)
+	if journalPath := o.closeJournal(); journalPath != "" {
+		fmt.Printf("Journal of this run: %s (undo with: picgroup undo %s)\n", journalPath, journalPath)
+	}
 	
-	org.Clear()
+	o.Clear()
 }
 
 // Clear resets the Organizer's state.
 func (o *Organizer) Clear() {
 	o.dateFolders = make(map[string]bool)
 	o.fEntries = make([]FileData, 0)
+	o.dateSources = make(map[DateSource]int)
+	o.corrections = nil
+	o.pending = nil
+	o.sequences = nil
+	o.renames = nil
+	o.claimed = nil
+	o.arriving = nil
+	o.collisions = nil
+	o.dedupIdx = nil
+	o.duplicates = nil
+	o.similarImages = nil
+	o.similarBest = nil
+	o.similarGroups = nil
+	o.sourceStats = nil
+	o.plan = nil
+	o.sidecars = nil
+	o.closeJournal()
+	o.closeCheckpoint(false)
+	o.finishedDirs = nil
+	o.finishedFiles = nil
+	o.failures.Store(0)
+}
+
+// printDateSources prints how many files took their capture date from each source.
+func (o *Organizer) printDateSources() {
+	sources := make([]string, 0, len(o.dateSources))
+	for source := range o.dateSources {
+		sources = append(sources, string(source))
+	}
+	sort.Strings(sources)
+	for _, source := range sources {
+		fmt.Printf("Date source %s: %d files\n", source, o.dateSources[DateSource(source)])
+	}
 }
 
 // ScanAndCreateFolders does a lightweight scan to create all needed folders without loading files into memory
 func (o *Organizer) ScanAndCreateFolders(fromPath string) {
 	o.scanForDateFolders(fromPath)
-	
+	o.createFolders()
+}
+
+// createFolders creates the generated folder and the date folders found by the scan.
+func (o *Organizer) createFolders() {
 	// Create the main generated folder
-	if err := o.genFolder(o.SrcPath, o.Generated); err != nil {
+	if err := o.genFolder(o.destRoot()); err != nil {
 		log.Printf("Error creating main folder: %v", err)
 		return
 	}
 
 	// Create subfolders for each date
 	for dateKey := range o.dateFolders {
-		if err := o.genFolder(o.SrcPath, o.Generated, dateKey); err != nil {
+		if err := o.genFolder(o.destRoot(), dateKey); err != nil {
 			log.Printf("Error creating subfolder: %v", err)
 		}
 	}
@@ -157,6 +278,9 @@ func (o *Organizer) ScanAndCreateFolders(fromPath string) {
 
 // scanForDateFolders recursively scans to find date folders needed, without storing file data
 func (o *Organizer) scanForDateFolders(fromPath string) {
+	if o.finished(fromPath) {
+		return
+	}
 	entries, err := os.ReadDir(fromPath)
 	if err != nil {
 		log.Printf("Error reading directory %s: %v", fromPath, err)
@@ -166,29 +290,19 @@ func (o *Organizer) scanForDateFolders(fromPath string) {
 	for _, entry := range entries {
 		fullPath := path.Join(fromPath, entry.Name())
 		if entry.IsDir() {
-			if entry.Name() != o.Generated && !strings.HasPrefix(entry.Name(), ".") && !strings.HasPrefix(entry.Name(), "@") {
+			if !o.skipDir(fullPath, entry.Name()) {
 				o.scanForDateFolders(fullPath)
 			}
-		} else {
-			// We don't need fileInfo, just check if we can read EXIF
-			infoStr := o.readMediaInfo(fullPath)
-			if infoStr != "" {
-				dateTimeOriginal := gjson.Get(infoStr, "DateTimeOriginal").String()
-				createTime, err := time.Parse("2006:01:02 15:04:05", dateTimeOriginal)
-				if err != nil {
-					continue
-				}
-
-				var newFolder string
-				switch o.FolderFormat {
-				case "ymd":
-					newFolder = createTime.Format("20060102")
-				case "ym":
-					newFolder = createTime.Format("200601")
-				default:
-					newFolder = createTime.Format("20060102")
-				}
+		} else if !o.finished(fullPath) {
+			// Finished files of a resumed run are not hashed, so they do not take part in
+			// near-duplicate groups.
+			// We don't need fileInfo, just check if the date chain yields a capture time
+			info, err := o.resolveDate(fullPath)
+			if err == nil {
+				newFolder := o.folderKey(fullPath, info)
 				o.dateFolders[newFolder] = true
+				o.addPendingRename(fullPath, info)
+				o.addSimilarCandidate(fullPath)
 			}
 		}
 	}
@@ -202,6 +316,10 @@ func (o *Organizer) ProcessFilesInBatches(fromPath string, workerCount int) {
 
 // processDirectoryInBatches recursively processes directories in small batches
 func (o *Organizer) processDirectoryInBatches(fromPath string, batchSize, workerCount int) {
+	if o.finished(fromPath) {
+		return
+	}
+	failures := o.failures.Load()
 	entries, err := os.ReadDir(fromPath)
 	if err != nil {
 		log.Printf("Error reading directory %s: %v", fromPath, err)
@@ -224,34 +342,29 @@ func (o *Organizer) processDirectoryInBatches(fromPath string, batchSize, worker
 				runtime.GC() // Force GC between batches
 			}
 			
-			if entry.Name() != o.Generated && !strings.HasPrefix(entry.Name(), ".") && !strings.HasPrefix(entry.Name(), "@") {
+			if !o.skipDir(fullPath, entry.Name()) {
 				o.processDirectoryInBatches(fullPath, batchSize, workerCount)
 			}
-		} else {
-			// Only read EXIF metadata, don't store file info
-			infoStr := o.readMediaInfo(fullPath)
-			if infoStr != "" {
-				dateTimeOriginal := gjson.Get(infoStr, "DateTimeOriginal").String()
-				createTime, err := time.Parse("2006:01:02 15:04:05", dateTimeOriginal)
-				if err != nil {
-					log.Println("Failed to parse date:", err)
-					continue
-				}
-
-				var newFolder string
-				switch o.FolderFormat {
-				case "ymd":
-					newFolder = createTime.Format("20060102")
-				case "ym":
-					newFolder = createTime.Format("200601")
-				default:
-					newFolder = createTime.Format("20060102")
+		} else if !o.finished(fullPath) {
+			// Only resolve the capture date, don't store file info
+			info, err := o.resolveDate(fullPath)
+			if err == nil {
+				newFolder := o.folderKey(fullPath, info)
+				name := o.fileName(fullPath, info)
+				newPath := path.Join(o.destRoot(), newFolder, name)
+				if reviewPath, ok := o.reviewPath(fullPath, name); ok {
+					newPath = reviewPath
 				}
 
 				batch = append(batch, FileData{
-					Path:    fullPath,
-					NewPath: path.Join(o.SrcPath, o.Generated, newFolder, filepath.Base(fullPath)),
+					Path:       fullPath,
+					NewPath:    newPath,
+					DateSource: info.DateSource,
+					Sidecars:   info.Sidecars,
 				})
+				o.countDateSource(info.DateSource)
+				o.recordCorrection(fullPath, info)
+				o.tally(fullPath, func(s *SourceSummary) { s.Files++ })
 
 				// Process batch when it reaches the size limit
 				if len(batch) >= batchSize {
@@ -268,6 +381,10 @@ func (o *Organizer) processDirectoryInBatches(fromPath string, batchSize, worker
 		o.processBatch(batch, workerCount)
 		runtime.GC()
 	}
+	// A folder with failed files is not finished; a resumed run tries them again.
+	if o.failures.Load() == failures {
+		o.writeCheckpoint(checkpointEntry{Op: checkpointDir, Path: fromPath})
+	}
 }
 
 // processBatch processes a small batch of files
@@ -323,6 +440,45 @@ func (o *Organizer) processBatch(batch []FileData, workerCount int) {
 	}
 }
 
+// AddFileEntries recursively collects the supported files under fromPath into memory for OrganizeFiles.
+// With a RenameFormat the new file names are assigned by OrganizeFiles, once all files are known.
+func (o *Organizer) AddFileEntries(fromPath string) {
+	entries, err := os.ReadDir(fromPath)
+	if err != nil {
+		log.Printf("Error reading directory %s: %v", fromPath, err)
+		return
+	}
+
+	for _, entry := range entries {
+		fullPath := path.Join(fromPath, entry.Name())
+		if entry.IsDir() {
+			if !o.skipDir(fullPath, entry.Name()) {
+				o.AddFileEntries(fullPath)
+			}
+			continue
+		}
+
+		info, err := o.resolveDate(fullPath)
+		if err != nil {
+			continue
+		}
+
+		newFolder := o.folderKey(fullPath, info)
+		o.dateFolders[newFolder] = true
+		o.fEntries = append(o.fEntries, FileData{
+			Path:       fullPath,
+			NewPath:    path.Join(o.destRoot(), newFolder, filepath.Base(fullPath)),
+			DateSource: info.DateSource,
+			Sidecars:   info.Sidecars,
+		})
+		o.countDateSource(info.DateSource)
+		o.recordCorrection(fullPath, info)
+		o.addPendingRename(fullPath, info)
+		o.addSimilarCandidate(fullPath)
+		o.tally(fullPath, func(s *SourceSummary) { s.Files++ })
+	}
+}
+
 // OrganizeFiles creates the necessary folders and processes file entries (either sequentially or concurrently).
 func (o *Organizer) OrganizeFiles(customWorkerCount int) {
 	if o.VerboseMode == "2" {
@@ -333,16 +489,18 @@ func (o *Organizer) OrganizeFiles(customWorkerCount int) {
 	if len(o.dateFolders) == 0 {
 		return
 	}
+	o.renameFileEntries()
+	o.routeSimilarEntries()
 
 	// Create the main generated folder.
-	if err := o.genFolder(o.SrcPath, o.Generated); err != nil {
+	if err := o.genFolder(o.destRoot()); err != nil {
 		log.Printf("Error creating folder: %v", err)
 		return
 	}
 
 	// Create subfolders for each date.
 	for dateKey := range o.dateFolders {
-		if err := o.genFolder(o.SrcPath, o.Generated, dateKey); err != nil {
+		if err := o.genFolder(o.destRoot(), dateKey); err != nil {
 			log.Printf("Error creating subfolder: %v", err)
 		}
 	}
@@ -434,11 +592,46 @@ func (o *Organizer) OrganizeFiles(customWorkerCount int) {
 	}
 }
 
-// genFolder creates a folder from the given path components.
+// destRoot returns the folder the organized library is built in.
+func (o *Organizer) destRoot() string {
+	if o.OutputRoot != "" {
+		return o.OutputRoot
+	}
+	return path.Join(o.SrcPath, o.Generated)
+}
+
+// skipDir reports whether the scan leaves out a folder: hidden folders, "@" folders of NAS
+// systems and the destination, when it is inside the source.
+func (o *Organizer) skipDir(dirPath, name string) bool {
+	if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "@") {
+		return true
+	}
+	if o.OutputRoot == "" {
+		return name == o.Generated
+	}
+	return samePath(dirPath, o.OutputRoot)
+}
+
+// samePath reports whether two paths name the same location.
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return absA == absB
+}
+
+// genFolder creates a folder from the given path components, including missing parents
+// of nested template folders.
 func (o *Organizer) genFolder(paths ...string) error {
+	if o.DryRun {
+		// Folders are created as files arrive when the plan is applied.
+		return nil
+	}
 	fullPath := path.Join(paths...)
 	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
-		err := os.Mkdir(fullPath, os.ModePerm)
+		err := o.mkdirAll(fullPath, os.ModePerm)
 		if err != nil {
 			return err
 		}
@@ -446,13 +639,29 @@ func (o *Organizer) genFolder(paths ...string) error {
 	return nil
 }
 
-// readMediaInfo obtains the media file's EXIF information via the overridable readMediaInfoFunc.
-func (o *Organizer) readMediaInfo(filePath string) string {
-	result := readMediaInfoFunc(filePath)
-	if result == "" && o.VerboseMode == "1" {
-		fmt.Println(strings.ToUpper(filepath.Ext(filePath)), "file not supported or invalid EXIF")
+// readMediaInfo obtains the media file's capture metadata via the organizer's extractor registry
+// and merges the dates of its XMP sidecar, if any.
+func (o *Organizer) readMediaInfo(filePath string) (*MediaInfo, error) {
+	registry := o.Extractors
+	if registry == nil {
+		registry = DefaultRegistry
+	}
+	info, err := registry.Extract(filePath)
+	if !errors.Is(err, ErrUnsupportedMedia) {
+		if sidecar, sidecarInfo, ok := readXMPSidecar(filePath); ok {
+			if info == nil {
+				info = &MediaInfo{}
+			}
+			info.Sidecars = append(info.Sidecars, sidecar)
+			if mergeXMPSidecar(info, sidecarInfo, o.XMPPrecedence) == nil {
+				err = nil
+			}
+		}
 	}
-	return result
+	if err != nil && o.VerboseMode == "1" {
+		fmt.Println(strings.ToUpper(filepath.Ext(filePath)), "file not supported or invalid EXIF:", err)
+	}
+	return info, err
 }
 
 // groupWorker processes file entries in the index range from start to end.
@@ -460,20 +669,9 @@ func (o *Organizer) groupWorker(start, end int) {
 	for i := start; i <= end; i++ {
 		if i < len(o.fEntries) {
 			if o.VerboseMode == "1" {
-				fmt.Printf("%s processing file %d: %s -> %s\n", o.GroupMode, i, o.fEntries[i].Path, o.fEntries[i].NewPath)
-			}
-			switch o.GroupMode {
-			case "copy":
-				_, err := o.copy(o.fEntries[i].Path, o.fEntries[i].NewPath)
-				if err != nil {
-					log.Printf("Error copying file: %v", err)
-				}
-			case "move":
-				_, err := o.move(o.fEntries[i].Path, o.fEntries[i].NewPath)
-				if err != nil {
-					log.Printf("Error moving file: %v", err)
-				}
+				fmt.Printf("%s processing file %d: %s -> %s (%s)\n", o.GroupMode, i, o.fEntries[i].Path, o.fEntries[i].NewPath, o.fEntries[i].DateSource)
 			}
+			o.groupFile(o.fEntries[i])
 		}
 	}
 }
@@ -481,28 +679,76 @@ func (o *Organizer) groupWorker(start, end int) {
 // processFile processes a single file entry according to the group mode
 func (o *Organizer) processFile(fileEntry FileData) {
 	if o.VerboseMode == "1" {
-		fmt.Printf("%s processing file: %s -> %s\n", o.GroupMode, fileEntry.Path, fileEntry.NewPath)
+		fmt.Printf("%s processing file: %s -> %s (%s)\n", o.GroupMode, fileEntry.Path, fileEntry.NewPath, fileEntry.DateSource)
+	}
+	if o.groupFile(fileEntry) {
+		o.checkpointDone(fileEntry.Path)
+	} else {
+		o.failures.Add(1)
 	}
+}
 
-	switch o.GroupMode {
-	case "copy":
-		_, err := o.copy(fileEntry.Path, fileEntry.NewPath)
-		if err != nil {
+// groupFile copies or moves a file entry and its sidecars according to the group mode, after
+// duplicates have been handled and the collision policy has decided about a destination that
+// is already taken. It returns false when the file could not be copied or moved.
+func (o *Organizer) groupFile(fileEntry FileData) bool {
+	if o.dedup(fileEntry) {
+		o.tally(fileEntry.Path, func(s *SourceSummary) { s.Duplicates++ })
+		return true
+	}
+	collision, ok := o.resolveCollision(fileEntry)
+	if !ok {
+		o.indexGrouped(fileEntry, false)
+		o.perform(PlanAction{Action: ActionSkip, Source: fileEntry.Path, Destination: collision.Target, DateSource: fileEntry.DateSource, Decision: collision.decision()})
+		o.tally(fileEntry.Path, func(s *SourceSummary) { s.Skipped++ })
+		return true
+	}
+	fileEntry.NewPath = collision.NewPath
+	placed := o.transfer(fileEntry, collision.decision(), collision.Action == CollisionOverwrite)
+	o.settleClaim(fileEntry, placed)
+	if !placed {
+		o.indexGrouped(fileEntry, false)
+		o.tally(fileEntry.Path, func(s *SourceSummary) { s.Failed++ })
+		return false
+	}
+	o.indexGrouped(fileEntry, true)
+	o.recordRename(fileEntry.Path, fileEntry.NewPath)
+	o.tally(fileEntry.Path, func(s *SourceSummary) { s.Grouped++ })
+	return true
+}
+
+// transfer copies or moves a file entry and its sidecars according to the group mode and
+// reports whether the file arrived.
+func (o *Organizer) transfer(fileEntry FileData, decision string, overwrite bool) bool {
+	o.checkpointStarted(fileEntry)
+	err := o.perform(PlanAction{
+		Action:      o.GroupMode,
+		Source:      fileEntry.Path,
+		Destination: fileEntry.NewPath,
+		DateSource:  fileEntry.DateSource,
+		Decision:    decision,
+		Overwrite:   overwrite,
+	})
+	if err != nil {
+		switch o.GroupMode {
+		case "copy":
 			log.Printf("Error copying file: %v", err)
-		}
-	case "move":
-		_, err := o.move(fileEntry.Path, fileEntry.NewPath)
-		if err != nil {
+		case "move":
 			log.Printf("Error moving file: %v", err)
+		default:
+			log.Printf("Error grouping file: %v", err)
 		}
+		return false
 	}
+	o.groupSidecars(fileEntry)
+	return true
 }
 
 // copy copies a file from src to dst with buffered I/O for performance
 func (o *Organizer) copy(src, dst string) (int64, error) {
 	// Create destination directory if it doesn't exist
 	dstDir := filepath.Dir(dst)
-	if err := os.MkdirAll(dstDir, 0755); err != nil {
+	if err := o.mkdirAll(dstDir, 0755); err != nil {
 		return 0, fmt.Errorf("failed to create destination directory: %v", err)
 	}
 
@@ -524,9 +770,14 @@ func (o *Organizer) copy(src, dst string) (int64, error) {
 	return nBytes, err
 }
 
-// move moves a file from src to dst.
+// move moves a file from src to dst, copying it when they are on different file systems.
 func (o *Organizer) move(src, dst string) (int64, error) {
-	err := os.Rename(src, dst)
+	// Create destination directory if it doesn't exist, as for folders outside the date folders
+	if err := o.mkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return 0, fmt.Errorf("failed to create destination directory: %v", err)
+	}
+
+	err := moveFile(src, dst)
 	if err != nil {
 		return 0, err
 	}
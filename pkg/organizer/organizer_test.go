@@ -201,7 +201,7 @@ func BenchmarkFileOrganizerSequential(b *testing.B) {
 	org := NewOrganizer(testDataDir, "ymd", "generated", "seq", "1", "copy")
 	for i := 0; i < b.N; i++ {
 		org.AddFileEntries(org.SrcPath)
-		org.OrganizeFiles()
+		org.OrganizeFiles(0)
 		org.Clear()
 	}
 }
@@ -217,7 +217,7 @@ func BenchmarkFileOrganizerConcurrent(b *testing.B) {
 	org := NewOrganizer(testDataDir, "ymd", "generated", "con", "1", "copy")
 	for i := 0; i < b.N; i++ {
 		org.AddFileEntries(org.SrcPath)
-		org.OrganizeFiles()
+		org.OrganizeFiles(0)
 		org.Clear()
 	}
 }
@@ -247,10 +247,10 @@ func TestReadMediaInfo(t *testing.T) {
 	defer os.Remove(testFilePath) // Clean up after test
 
 	org := NewOrganizer("", "", "", "", "1", "")
-	info := org.readMediaInfo(testFilePath)
+	info, err := org.readMediaInfo(testFilePath)
 	// Since this is an empty file, we expect no EXIF info
-	if info != "" {
-		t.Errorf("Expected empty string for file with no EXIF, got %s", info)
+	if err == nil {
+		t.Errorf("Expected an error for file with no EXIF, got %+v", info)
 	}
 }
 
@@ -311,3 +311,43 @@ func TestMove(t *testing.T) {
 		t.Errorf("Expected content %s, got %s", string(content), string(dstContent))
 	}
 }
+
+func TestOutputRoot(t *testing.T) {
+	src, library := t.TempDir(), t.TempDir()
+	writeTestFiles(t, src, "IMG_20230501_143000.xyz")
+
+	org := NewOrganizer(src, "ymd", "generated", "seq", "0", "move")
+	org.Extractors = testChainRegistry()
+	org.OutputRoot = library
+	org.Run(0)
+
+	if _, err := os.Stat(filepath.Join(library, "20230501", "IMG_20230501_143000.xyz")); err != nil {
+		t.Errorf("Expected the file in the output root: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(src, "generated")); !os.IsNotExist(err) {
+		t.Errorf("Expected no generated folder in the source, got %v", err)
+	}
+}
+
+func TestOutputRootInsideSource(t *testing.T) {
+	src := t.TempDir()
+	library := filepath.Join(src, "Library")
+	if err := os.MkdirAll(filepath.Join(library, "20220101"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFiles(t, filepath.Join(library, "20220101"), "IMG_20230501_090000.xyz")
+	writeTestFiles(t, src, "IMG_20230501_143000.xyz")
+
+	org := NewOrganizer(src, "ymd", "generated", "seq", "0", "move")
+	org.Extractors = testChainRegistry()
+	org.OutputRoot = library
+	org.Run(0)
+
+	if _, err := os.Stat(filepath.Join(library, "20230501", "IMG_20230501_143000.xyz")); err != nil {
+		t.Errorf("Expected the file in the output root: %v", err)
+	}
+	// The library inside the source is not scanned again.
+	if _, err := os.Stat(filepath.Join(library, "20220101", "IMG_20230501_090000.xyz")); err != nil {
+		t.Errorf("Expected the library file to stay in place: %v", err)
+	}
+}
@@ -0,0 +1,332 @@
+package organizer
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"math/bits"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	// Decoders for the formats perceptual hashing understands.
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// --- Perceptual near-duplicate detection ---
+
+// Perceptual hashes compare what photos look like rather than their bytes, so resized and
+// re-encoded copies hash alike.
+const (
+	PerceptualOff   = "off"
+	PerceptualDHash = "dhash" // brightness gradients between neighbouring cells; fast
+	PerceptualPHash = "phash" // low frequencies of a discrete cosine transform; more robust
+)
+
+// DefaultSimilarityThreshold is the number of the 64 hash bits two photos may differ in and
+// still count as near-duplicates.
+const DefaultSimilarityThreshold = 10
+
+// ReviewFolder is the folder under the generated folder that receives all but the best copy of
+// each group of near-duplicates, in a folder named after the best copy.
+const ReviewFolder = "Review"
+
+// SimilarGroup is a group of near-duplicate photos.
+type SimilarGroup struct {
+	Best   string   // copy with the largest resolution, then file size, which is organized as usual
+	Others []string // copies routed to the review folder
+}
+
+// similarImage is a hashed photo waiting for the groups to be formed.
+type similarImage struct {
+	path   string
+	hash   uint64
+	pixels int
+	size   int64
+}
+
+// ParsePerceptualHash checks a perceptual hash name; "" is PerceptualOff.
+func ParsePerceptualHash(s string) (string, error) {
+	switch s {
+	case "", PerceptualOff:
+		return PerceptualOff, nil
+	case PerceptualDHash, PerceptualPHash:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown perceptual hash %q (off/dhash/phash)", s)
+}
+
+// perceptualEnabled reports whether near-duplicates are looked for.
+func (o *Organizer) perceptualEnabled() bool {
+	hash, err := ParsePerceptualHash(o.PerceptualHash)
+	return err == nil && hash != PerceptualOff
+}
+
+// addSimilarCandidate hashes a photo the standard library can decode so it can be grouped with
+// its near-duplicates once the scan is complete.
+func (o *Organizer) addSimilarCandidate(filePath string) {
+	if !o.perceptualEnabled() {
+		return
+	}
+	f, err := os.Open(filePath)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	// Reading the header first keeps other files from being decoded.
+	if _, _, err := image.DecodeConfig(f); err != nil {
+		return
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		return
+	}
+	img, _, err := image.Decode(f)
+	if err != nil {
+		if o.VerboseMode == "1" {
+			fmt.Printf("Error decoding %s for perceptual hashing: %v\n", filePath, err)
+		}
+		return
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		return
+	}
+
+	hash := dHash(img)
+	if o.PerceptualHash == PerceptualPHash {
+		hash = pHash(img)
+	}
+	bounds := img.Bounds()
+	o.similarImages = append(o.similarImages, similarImage{path: filePath, hash: hash, pixels: bounds.Dx() * bounds.Dy(), size: stat.Size()})
+	o.similarBest = nil
+}
+
+// groupSimilar groups the hashed photos and picks the best copy of each group. The photos are
+// taken best first, and each joins the group whose best copy is nearest to it within the
+// similarity threshold, or starts a group of its own. Comparing with the best copy only keeps
+// a chain of small changes from pulling unrelated photos into one group.
+func (o *Organizer) groupSimilar() {
+	images := append([]similarImage(nil), o.similarImages...)
+	sort.Slice(images, func(i, j int) bool {
+		a, b := images[i], images[j]
+		switch {
+		case a.pixels != b.pixels:
+			return a.pixels > b.pixels
+		case a.size != b.size:
+			return a.size > b.size
+		}
+		return a.path < b.path
+	})
+
+	var groups []SimilarGroup
+	var best *bkNode
+	for _, img := range images {
+		if group, ok := best.nearest(img.hash, o.SimilarityThreshold); ok {
+			groups[group].Others = append(groups[group].Others, img.path)
+			continue
+		}
+		best = best.insert(img.hash, len(groups))
+		groups = append(groups, SimilarGroup{Best: img.path})
+	}
+
+	o.similarBest = make(map[string]string)
+	o.similarGroups = nil
+	for _, group := range groups {
+		if len(group.Others) == 0 {
+			continue
+		}
+		for _, other := range group.Others {
+			o.similarBest[other] = group.Best
+		}
+		o.similarGroups = append(o.similarGroups, group)
+	}
+	sort.Slice(o.similarGroups, func(i, j int) bool { return o.similarGroups[i].Best < o.similarGroups[j].Best })
+}
+
+// bkNode is a node of a BK-tree, which finds the hashes within a Hamming distance of a hash
+// without comparing it with all of them. A nil tree is empty.
+type bkNode struct {
+	hash     uint64
+	group    int
+	children map[int]*bkNode // by distance to hash
+}
+
+// insert adds the hash of the best copy of a group and returns the tree.
+func (n *bkNode) insert(hash uint64, group int) *bkNode {
+	node := &bkNode{hash: hash, group: group}
+	if n == nil {
+		return node
+	}
+	for parent := n; ; {
+		d := hammingDistance(parent.hash, hash)
+		child, ok := parent.children[d]
+		if !ok {
+			if parent.children == nil {
+				parent.children = make(map[int]*bkNode)
+			}
+			parent.children[d] = node
+			return n
+		}
+		parent = child
+	}
+}
+
+// nearest returns the group whose hash is nearest to hash within threshold; between groups as
+// near, the first one.
+func (n *bkNode) nearest(hash uint64, threshold int) (int, bool) {
+	group, distance := -1, threshold+1
+	var search func(*bkNode)
+	search = func(node *bkNode) {
+		d := hammingDistance(node.hash, hash)
+		if d < distance || d == distance && node.group < group {
+			group, distance = node.group, d
+		}
+		// Only subtrees at a distance within threshold of d can hold a hash within threshold.
+		for childDistance, child := range node.children {
+			if childDistance >= d-threshold && childDistance <= d+threshold {
+				search(child)
+			}
+		}
+	}
+	if n != nil {
+		search(n)
+	}
+	return group, group >= 0
+}
+
+// reviewPath returns where a near-duplicate that is not the best copy of its group goes, or
+// false when the file is organized as usual.
+func (o *Organizer) reviewPath(filePath, name string) (string, bool) {
+	if !o.perceptualEnabled() {
+		return "", false
+	}
+	if o.similarBest == nil {
+		o.groupSimilar()
+	}
+	best, ok := o.similarBest[filePath]
+	if !ok {
+		return "", false
+	}
+	bestName := filepath.Base(best)
+	folder := sanitizePathComponent(strings.TrimSuffix(bestName, filepath.Ext(bestName)))
+	return path.Join(o.destRoot(), ReviewFolder, folder, name), true
+}
+
+// routeSimilarEntries sends the near-duplicates collected by AddFileEntries to the review folder.
+func (o *Organizer) routeSimilarEntries() {
+	for i, entry := range o.fEntries {
+		if newPath, ok := o.reviewPath(entry.Path, filepath.Base(entry.NewPath)); ok {
+			o.fEntries[i].NewPath = newPath
+		}
+	}
+}
+
+// SimilarGroups returns the groups of near-duplicates found in the current run.
+func (o *Organizer) SimilarGroups() []SimilarGroup {
+	return o.similarGroups
+}
+
+// printSimilarGroups reports the groups of near-duplicates and which copy was kept.
+func (o *Organizer) printSimilarGroups() {
+	if len(o.similarGroups) == 0 {
+		return
+	}
+	fmt.Printf("Found %d groups of near-duplicates, others routed to %s for review:\n", len(o.similarGroups), ReviewFolder)
+	for _, group := range o.similarGroups {
+		fmt.Printf("  %s (best): %s\n", group.Best, strings.Join(group.Others, ", "))
+	}
+}
+
+// hammingDistance counts the bits two hashes differ in.
+func hammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// grayGrid shrinks an image to a w x h grid of average brightness. Large images are sampled
+// rather than read pixel by pixel.
+func grayGrid(img image.Image, w, h int) [][]float64 {
+	bounds := img.Bounds()
+	const samples = 8 // per cell and axis
+	grid := make([][]float64, h)
+	for y := 0; y < h; y++ {
+		grid[y] = make([]float64, w)
+		for x := 0; x < w; x++ {
+			var sum float64
+			for sy := 0; sy < samples; sy++ {
+				py := bounds.Min.Y + ((y*samples+sy)*bounds.Dy()+bounds.Dy()/2)/(h*samples)
+				for sx := 0; sx < samples; sx++ {
+					px := bounds.Min.X + ((x*samples+sx)*bounds.Dx()+bounds.Dx()/2)/(w*samples)
+					sum += luma(img, px, py)
+				}
+			}
+			grid[y][x] = sum / (samples * samples)
+		}
+	}
+	return grid
+}
+
+// luma returns the brightness of a pixel, reading the luma plane of JPEG images directly.
+func luma(img image.Image, x, y int) float64 {
+	if ycc, ok := img.(*image.YCbCr); ok {
+		return float64(ycc.Y[ycc.YOffset(x, y)]) * 257
+	}
+	r, g, b, _ := img.At(x, y).RGBA()
+	return 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+}
+
+// dHash is the difference hash: each bit tells whether a cell of a 9x8 grid is brighter than
+// its right neighbour.
+func dHash(img image.Image) uint64 {
+	grid := grayGrid(img, 9, 8)
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if grid[y][x] > grid[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// pHash is the perceptual hash: each bit tells whether one of the 8x8 lowest frequencies of the
+// 32x32 discrete cosine transform is above their median.
+func pHash(img image.Image) uint64 {
+	const n = 32
+	grid := grayGrid(img, n, n)
+
+	var coeffs [64]float64
+	for u := 0; u < 8; u++ {
+		for v := 0; v < 8; v++ {
+			var sum float64
+			for y := 0; y < n; y++ {
+				for x := 0; x < n; x++ {
+					sum += grid[y][x] *
+						math.Cos(float64(2*x+1)*float64(v)*math.Pi/(2*n)) *
+						math.Cos(float64(2*y+1)*float64(u)*math.Pi/(2*n))
+				}
+			}
+			coeffs[u*8+v] = sum
+		}
+	}
+
+	// The DC term only carries the overall brightness and is left out of the median.
+	sorted := append([]float64(nil), coeffs[1:]...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+
+	var hash uint64
+	for _, c := range coeffs {
+		hash <<= 1
+		if c > median {
+			hash |= 1
+		}
+	}
+	return hash
+}
@@ -0,0 +1,144 @@
+package organizer
+
+import (
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testScene draws a w x h picture of soft blobs, mirrored when flip is set.
+func testScene(w, h int, flip bool) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			fx, fy := float64(x)/float64(w), float64(y)/float64(h)
+			if flip {
+				fx = 1 - fx
+			}
+			v := 128 + 60*math.Sin(fx*7) + 60*math.Cos(fy*5+fx*2)
+			img.Set(x, y, color.RGBA{uint8(v), uint8(v * 0.8), uint8(255 - v), 255})
+		}
+	}
+	return img
+}
+
+// writeTestImage encodes an image as PNG, or as JPEG at the given quality.
+func writeTestImage(t *testing.T, filePath string, img image.Image, quality int) {
+	t.Helper()
+	f, err := os.Create(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if quality > 0 {
+		err = jpeg.Encode(f, img, &jpeg.Options{Quality: quality})
+	} else {
+		err = png.Encode(f, img)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNearDuplicates(t *testing.T) {
+	for _, hash := range []string{PerceptualDHash, PerceptualPHash} {
+		dir := t.TempDir()
+		writeTestImage(t, filepath.Join(dir, "IMG_20230501_143000.xyz"), testScene(320, 240, false), 0)
+		// The same photo as a messenger would pass it on: smaller and recompressed.
+		writeTestImage(t, filepath.Join(dir, "IMG-20230502-WA0001.xyz"), testScene(160, 120, false), 40)
+		writeTestImage(t, filepath.Join(dir, "IMG_20230501_150000.xyz"), testScene(320, 240, true), 0)
+
+		org := NewOrganizer(dir, "ymd", "generated", "seq", "0", "move")
+		org.Extractors = testChainRegistry()
+		org.PerceptualHash = hash
+		org.AddFileEntries(dir)
+		org.OrganizeFiles(0)
+
+		groups := org.SimilarGroups()
+		if len(groups) != 1 || filepath.Base(groups[0].Best) != "IMG_20230501_143000.xyz" ||
+			len(groups[0].Others) != 1 || filepath.Base(groups[0].Others[0]) != "IMG-20230502-WA0001.xyz" {
+			t.Fatalf("%s: unexpected groups %+v", hash, groups)
+		}
+		for _, name := range []string{
+			"20230501/IMG_20230501_143000.xyz",
+			"20230501/IMG_20230501_150000.xyz",
+			ReviewFolder + "/IMG_20230501_143000/IMG-20230502-WA0001.xyz",
+		} {
+			if _, err := os.Stat(filepath.Join(dir, "generated", filepath.FromSlash(name))); err != nil {
+				t.Errorf("%s: expected %s: %v", hash, name, err)
+			}
+		}
+	}
+}
+
+func TestPerceptualHashDistance(t *testing.T) {
+	a, b, other := testScene(400, 300, false), testScene(200, 150, false), testScene(400, 300, true)
+	for name, hash := range map[string]func(image.Image) uint64{"dhash": dHash, "phash": pHash} {
+		if d := hammingDistance(hash(a), hash(b)); d > DefaultSimilarityThreshold {
+			t.Errorf("%s: resized copy is %d bits away", name, d)
+		}
+		if d := hammingDistance(hash(a), hash(other)); d <= DefaultSimilarityThreshold {
+			t.Errorf("%s: different photo is only %d bits away", name, d)
+		}
+	}
+}
+
+func TestSimilarityThresholdZero(t *testing.T) {
+	org := NewOrganizer(t.TempDir(), "ymd", "generated", "seq", "0", "move")
+	org.PerceptualHash = PerceptualDHash
+	org.SimilarityThreshold = 0
+	org.similarImages = []similarImage{
+		{path: "a.jpg", hash: 0xf0f0, pixels: 100},
+		{path: "b.jpg", hash: 0xf0f0, pixels: 50},
+		{path: "c.jpg", hash: 0xf0f1, pixels: 50},
+	}
+	org.groupSimilar()
+	if groups := org.SimilarGroups(); len(groups) != 1 || groups[0].Best != "a.jpg" || len(groups[0].Others) != 1 || groups[0].Others[0] != "b.jpg" {
+		t.Errorf("Expected only the photos that hash alike to be grouped, got %+v", groups)
+	}
+}
+
+func TestSimilarGroupsDoNotChain(t *testing.T) {
+	org := NewOrganizer(t.TempDir(), "ymd", "generated", "seq", "0", "move")
+	org.PerceptualHash = PerceptualDHash
+	// Each photo is within the threshold of the next one, but the last is not of the first.
+	org.similarImages = []similarImage{
+		{path: "a.jpg", hash: 0, pixels: 300},
+		{path: "b.jpg", hash: 0x3f, pixels: 200},
+		{path: "c.jpg", hash: 0xfff, pixels: 100},
+	}
+	org.groupSimilar()
+	if groups := org.SimilarGroups(); len(groups) != 1 || groups[0].Best != "a.jpg" || len(groups[0].Others) != 1 || groups[0].Others[0] != "b.jpg" {
+		t.Errorf("Expected c.jpg to stay out of the group of a.jpg, got %+v", groups)
+	}
+}
+
+func TestBKTreeNearest(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var tree *bkNode
+	var hashes []uint64
+	for i := 0; i < 500; i++ {
+		// Few bits set, so many hashes are near each other.
+		hash := rng.Uint64() & rng.Uint64() & rng.Uint64() & rng.Uint64()
+		tree = tree.insert(hash, i)
+		hashes = append(hashes, hash)
+	}
+	for i := 0; i < 200; i++ {
+		hash := rng.Uint64() & rng.Uint64() & rng.Uint64() & rng.Uint64()
+		want, distance := -1, DefaultSimilarityThreshold+1
+		for group, h := range hashes {
+			if d := hammingDistance(h, hash); d < distance {
+				want, distance = group, d
+			}
+		}
+		if got, ok := tree.nearest(hash, DefaultSimilarityThreshold); got != want || ok != (want >= 0) {
+			t.Fatalf("nearest(%x) = %d, %v; want %d", hash, got, ok, want)
+		}
+	}
+}
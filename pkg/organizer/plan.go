@@ -0,0 +1,219 @@
+package organizer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// --- Dry runs and plans ---
+
+// Plan actions are the changes on disk a run makes.
+const (
+	ActionCopy   = "copy"   // copy Source to Destination
+	ActionMove   = "move"   // move Source to Destination
+	ActionLink   = "link"   // link Destination to Source, a file already in the library
+	ActionRemove = "remove" // remove Source; Destination, if given, is a copy the library keeps
+	ActionSkip   = "skip"   // leave Source where it is; nothing to do
+)
+
+// PlanAction is one step of a plan. A dry run writes the steps it would take as a plan, which
+// ApplyPlan later executes exactly.
+type PlanAction struct {
+	Action      string     `json:"action"`
+	Source      string     `json:"source"`
+	Destination string     `json:"destination,omitempty"`
+	DateSource  DateSource `json:"date_source,omitempty"`
+	Decision    string     `json:"decision,omitempty"`  // how a collision or duplicate was resolved
+	Overwrite   bool       `json:"overwrite,omitempty"` // Destination may be replaced
+}
+
+// planColumns are the columns of a CSV plan.
+var planColumns = []string{"action", "source", "destination", "date_source", "decision", "overwrite"}
+
+// perform carries out an action, or only adds it to the plan in a dry run.
+func (o *Organizer) perform(action PlanAction) error {
+	if o.DryRun {
+		o.planMu.Lock()
+		o.plan = append(o.plan, action)
+		o.planMu.Unlock()
+		return nil
+	}
+	return o.execute(action)
+}
+
+// execute carries out an action on disk and journals it. Unless the action allows it, an existing
+// destination is never replaced; in a journaled run, a replaced file is kept for undo.
+func (o *Organizer) execute(action PlanAction) error {
+	if action.Action != ActionSkip && action.Action != ActionRemove {
+		if _, err := os.Lstat(action.Destination); err == nil {
+			if !action.Overwrite {
+				return fmt.Errorf("destination %s exists", action.Destination)
+			}
+			// The replaced file is kept for undo.
+			if _, err := o.keep(action.Destination); err != nil {
+				return fmt.Errorf("keeping replaced %s: %w", action.Destination, err)
+			}
+		}
+	}
+
+	var err error
+	switch action.Action {
+	case ActionCopy:
+		if _, err = o.copy(action.Source, action.Destination); err == nil {
+			o.recordFile(ActionCopy, action.Source, action.Destination, action.Destination)
+		}
+	case ActionMove:
+		if _, err = o.move(action.Source, action.Destination); err == nil {
+			o.recordFile(ActionMove, action.Source, action.Destination, action.Destination)
+		}
+	case ActionLink:
+		if err = o.mkdirAll(filepath.Dir(action.Destination), 0755); err != nil {
+			break
+		}
+		if err = linkFile(action.Source, action.Destination); err == nil {
+			o.recordFile(ActionLink, action.Source, action.Destination, action.Destination)
+		}
+	case ActionRemove:
+		if action.Destination == "" {
+			// Without a copy in the library, undo needs the file itself.
+			var kept bool
+			if kept, err = o.keep(action.Source); kept || err != nil {
+				break
+			}
+		}
+		stat, statErr := os.Stat(action.Source)
+		if err = os.Remove(action.Source); err == nil && statErr == nil {
+			o.record(JournalEntry{Op: ActionRemove, Source: action.Source, Destination: action.Destination, Size: stat.Size(), ModTime: stat.ModTime().UnixNano()})
+		}
+	case ActionSkip:
+	default:
+		err = fmt.Errorf("unknown action %q", action.Action)
+	}
+	return err
+}
+
+// joinDecisions joins the non-empty parts of a decision.
+func joinDecisions(parts ...string) string {
+	var kept []string
+	for _, part := range parts {
+		if part != "" {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, "; ")
+}
+
+// Plan returns the actions of the current dry run.
+func (o *Organizer) Plan() []PlanAction {
+	return o.plan
+}
+
+// WritePlan writes a plan as CSV when the file name ends in ".csv", as JSON otherwise.
+func WritePlan(planPath string, actions []PlanAction) error {
+	f, err := os.Create(planPath)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(filepath.Ext(planPath), ".csv") {
+		err = writePlanCSV(f, actions)
+	} else {
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(actions)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// writePlanCSV writes a plan as CSV with a header row.
+func writePlanCSV(w io.Writer, actions []PlanAction) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(planColumns); err != nil {
+		return err
+	}
+	for _, a := range actions {
+		overwrite := ""
+		if a.Overwrite {
+			overwrite = "true"
+		}
+		if err := writer.Write([]string{a.Action, a.Source, a.Destination, string(a.DateSource), a.Decision, overwrite}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// ReadPlan reads a plan written by WritePlan, possibly edited by hand.
+func ReadPlan(planPath string) ([]PlanAction, error) {
+	f, err := os.Open(planPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if !strings.EqualFold(filepath.Ext(planPath), ".csv") {
+		var actions []PlanAction
+		if err := json.NewDecoder(f).Decode(&actions); err != nil {
+			return nil, fmt.Errorf("reading plan %s: %w", planPath, err)
+		}
+		return actions, nil
+	}
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("reading plan %s: %w", planPath, err)
+	}
+	var actions []PlanAction
+	for i, record := range records {
+		if i == 0 && len(record) > 0 && record[0] == planColumns[0] {
+			continue
+		}
+		// Short rows leave the trailing columns empty.
+		for len(record) < len(planColumns) {
+			record = append(record, "")
+		}
+		action := PlanAction{Action: record[0], Source: record[1], Destination: record[2], DateSource: DateSource(record[3]), Decision: record[4]}
+		if record[5] != "" {
+			if action.Overwrite, err = strconv.ParseBool(record[5]); err != nil {
+				return nil, fmt.Errorf("reading plan %s: line %d: invalid overwrite %q", planPath, i+1, record[5])
+			}
+		}
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
+
+// ApplyPlan executes the actions of a plan in order, journaling them to JournalPath when it is
+// set. An action that fails is reported and the rest of the plan still runs; the returned error
+// joins all failures.
+func (o *Organizer) ApplyPlan(actions []PlanAction) error {
+	o.startJournal(o.JournalPath)
+
+	var errs []error
+	for i, action := range actions {
+		if o.VerboseMode == "1" {
+			fmt.Printf("%s %s -> %s\n", action.Action, action.Source, action.Destination)
+		}
+		if err := o.execute(action); err != nil {
+			log.Printf("Error applying step %d: %v", i+1, err)
+			errs = append(errs, fmt.Errorf("step %d (%s %s): %w", i+1, action.Action, action.Source, err))
+		}
+	}
+	if journalPath := o.closeJournal(); journalPath != "" {
+		fmt.Printf("Journal of this plan: %s (undo with: picgroup undo %s)\n", journalPath, journalPath)
+	}
+	return errors.Join(errs...)
+}
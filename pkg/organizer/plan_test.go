@@ -0,0 +1,155 @@
+package organizer
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDryRunPlan(t *testing.T) {
+	src := t.TempDir()
+	writeTestFiles(t, src, "IMG_20230501_143000.xyz", "IMG_20230502_100000.xyz")
+	generated := filepath.Join(src, "generated")
+	planPath := filepath.Join(t.TempDir(), "plan.json")
+
+	org := NewOrganizer(src, "ymd", "generated", "seq", "0", "move")
+	org.Extractors = testChainRegistry()
+	org.DryRun = true
+	org.PlanPath = planPath
+	org.Run(0)
+
+	if _, err := os.Stat(generated); !os.IsNotExist(err) {
+		t.Fatalf("Expected a dry run to create nothing, got %v", err)
+	}
+	for _, name := range []string{"IMG_20230501_143000.xyz", "IMG_20230502_100000.xyz"} {
+		if _, err := os.Stat(filepath.Join(src, name)); err != nil {
+			t.Errorf("Expected %s to stay in place: %v", name, err)
+		}
+	}
+
+	actions, err := ReadPlan(planPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 2 {
+		t.Fatalf("Expected 2 actions, got %+v", actions)
+	}
+	for _, a := range actions {
+		if a.Action != ActionMove || a.DateSource != DateSourceFilename || filepath.Dir(filepath.Dir(a.Destination)) != generated {
+			t.Errorf("Unexpected action %+v", a)
+		}
+	}
+
+	// Edit the plan: the second file goes elsewhere.
+	actions[1].Destination = filepath.Join(generated, "picked", "chosen.xyz")
+	if err := org.ApplyPlan(actions); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(actions[0].Destination); err != nil {
+		t.Errorf("Expected the planned destination: %v", err)
+	}
+	if _, err := os.Stat(actions[1].Destination); err != nil {
+		t.Errorf("Expected the edited destination: %v", err)
+	}
+}
+
+func TestDryRunCollisionDecision(t *testing.T) {
+	src := t.TempDir()
+	writeTestFiles(t, src, "IMG_20230501_143000.xyz")
+	existing := filepath.Join(src, "generated", "20230501")
+	if err := os.MkdirAll(existing, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFiles(t, existing, "IMG_20230501_143000.xyz")
+
+	org := NewOrganizer(src, "ymd", "generated", "seq", "0", "copy")
+	org.Extractors = testChainRegistry()
+	org.CollisionPolicy = CollisionOverwrite
+	org.DryRun = true
+	org.AddFileEntries(src)
+	org.OrganizeFiles(0)
+
+	plan := org.Plan()
+	if len(plan) != 1 || !plan[0].Overwrite || plan[0].Decision != "collision: overwrite" {
+		t.Fatalf("Expected an overwrite decision, got %+v", plan)
+	}
+}
+
+func TestApplyPlanRefusesExistingDestination(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, "a.xyz", "b.xyz")
+	a, b := filepath.Join(dir, "a.xyz"), filepath.Join(dir, "b.xyz")
+	if err := os.WriteFile(b, []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	org := NewOrganizer(dir, "ymd", "generated", "seq", "0", "copy")
+	if err := org.ApplyPlan([]PlanAction{{Action: ActionCopy, Source: a, Destination: b}}); err == nil {
+		t.Error("Expected an existing destination to be refused")
+	}
+	if got, _ := os.ReadFile(b); string(got) != "keep" {
+		t.Errorf("Expected the destination to be kept, got %q", got)
+	}
+	if err := org.ApplyPlan([]PlanAction{{Action: ActionCopy, Source: a, Destination: b, Overwrite: true}}); err != nil {
+		t.Errorf("Expected an overwrite to be applied: %v", err)
+	}
+}
+
+func TestPlanFormats(t *testing.T) {
+	want := []PlanAction{
+		{Action: ActionMove, Source: "/in/a, b.jpg", Destination: "/out/20230501/a, b.jpg", DateSource: DateSourceExifOriginal},
+		{Action: ActionSkip, Source: "/in/c.jpg", Destination: "/out/20230501/c.jpg", Decision: "collision: skip (identical content)"},
+		{Action: ActionLink, Source: "/out/20230501/a, b.jpg", Destination: "/out/20230502/d.jpg", Overwrite: true},
+		{Action: ActionRemove, Source: "/in/d.jpg"},
+	}
+	for _, name := range []string{"plan.json", "plan.csv"} {
+		planPath := filepath.Join(t.TempDir(), name)
+		if err := WritePlan(planPath, want); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ReadPlan(planPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %+v, want %+v", name, got, want)
+		}
+	}
+}
+
+func TestDryRunSharedSidecar(t *testing.T) {
+	src := t.TempDir()
+	writeTestFiles(t, src, "IMG_20230501_143000.xyz", "IMG_20230501_143000.abc")
+	sidecar := filepath.Join(src, "IMG_20230501_143000.xmp")
+	if err := os.WriteFile(sidecar, []byte(testXMPPacket(`xmp:CreateDate="2023-05-01T14:30:00">`)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A RAW+JPEG pair shares the sidecar.
+	org := NewOrganizer(src, "ymd", "generated", "seq", "0", "move")
+	org.Extractors = testChainRegistry()
+	org.Extractors.Register("application/x-test-raw", ExtractorFunc(func(r io.ReadSeeker) (*MediaInfo, error) {
+		return &MediaInfo{}, nil
+	}), ".abc")
+	org.DryRun = true
+	org.AddFileEntries(src)
+	org.OrganizeFiles(0)
+
+	var sidecarActions int
+	for _, a := range org.Plan() {
+		if a.Source == sidecar {
+			sidecarActions++
+		}
+	}
+	if sidecarActions != 1 {
+		t.Fatalf("Expected the shared sidecar to be planned once, got %+v", org.Plan())
+	}
+	if err := org.ApplyPlan(org.Plan()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(src, "generated", "20230501", "IMG_20230501_143000.xmp")); err != nil {
+		t.Errorf("Expected the sidecar to be moved: %v", err)
+	}
+}
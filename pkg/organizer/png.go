@@ -0,0 +1,198 @@
+package organizer
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+	"strings"
+	"time"
+)
+
+// --- PNG capture dates ---
+
+// DateSourcePNGCreationTime is the "Creation Time" text keyword of a PNG file.
+const DateSourcePNGCreationTime DateSource = "png:CreationTime"
+
+// pngSignature starts every PNG file.
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+// maxPNGTextChunk bounds the size of text and eXIf chunks loaded into memory.
+const maxPNGTextChunk = 4 * 1024 * 1024
+
+// pngCreationTimeLayouts lists the formats seen in "Creation Time" text chunks.
+var pngCreationTimeLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"2 Jan 2006 15:04:05 -0700",
+	"2006-01-02T15:04:05Z07:00",
+}
+
+// extractPNG reads capture metadata from the eXIf, tEXt, zTXt and iTXt chunks of a PNG image.
+// Dates are ranked like JPEG: DateTimeOriginal, then CreateDate, then modification and text times.
+func extractPNG(r io.ReadSeeker) (*MediaInfo, error) {
+	signature := make([]byte, 8)
+	if _, err := io.ReadFull(r, signature); err != nil || string(signature) != pngSignature {
+		return nil, ErrNoCaptureDate
+	}
+
+	info := &MediaInfo{}
+	var candidates []DateCandidate
+	header := make([]byte, 8)
+
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			break
+		}
+		length := int64(binary.BigEndian.Uint32(header[0:4]))
+		chunkType := string(header[4:8])
+		if chunkType == "IEND" {
+			break
+		}
+
+		switch chunkType {
+		case "IHDR", "eXIf", "tEXt", "zTXt", "iTXt":
+			if length > maxPNGTextChunk {
+				break
+			}
+			data := make([]byte, length)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return nil, ErrNoCaptureDate
+			}
+			candidates = append(candidates, readPNGChunk(chunkType, data, info)...)
+			// Skip the CRC.
+			if _, err := r.Seek(4, io.SeekCurrent); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if _, err := r.Seek(length+4, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+	}
+
+	return info, info.setDates(candidates)
+}
+
+// readPNGChunk reads one metadata chunk, filling the camera fields of info and returning any dates found.
+func readPNGChunk(chunkType string, data []byte, info *MediaInfo) []DateCandidate {
+	switch chunkType {
+	case "IHDR":
+		if len(data) >= 8 {
+			info.Width = int(binary.BigEndian.Uint32(data[0:4]))
+			info.Height = int(binary.BigEndian.Uint32(data[4:8]))
+		}
+	case "eXIf":
+		data = bytes.TrimPrefix(data, []byte("Exif\x00\x00"))
+		exifInfo, _ := mediaInfoFromExif(data)
+		if exifInfo == nil {
+			return nil
+		}
+		mergeCameraInfo(info, exifInfo)
+		return exifInfo.Dates
+	default:
+		keyword, text, ok := pngText(chunkType, data)
+		if !ok {
+			return nil
+		}
+		switch keyword {
+		case "XML:com.adobe.xmp":
+			if xmpInfo, _ := mediaInfoFromXMP([]byte(text)); xmpInfo != nil {
+				mergeCameraInfo(info, xmpInfo)
+			}
+			return parseXMPDates([]byte(text))
+		case "Creation Time":
+			if t, loc, ok := parsePNGCreationTime(text); ok {
+				return []DateCandidate{zonedCandidate(t, loc, DateSourcePNGCreationTime)}
+			}
+		}
+	}
+	return nil
+}
+
+// pngText decodes the keyword and text of a tEXt, zTXt or iTXt chunk.
+func pngText(chunkType string, data []byte) (string, string, bool) {
+	nul := bytes.IndexByte(data, 0)
+	if nul < 0 {
+		return "", "", false
+	}
+	keyword, rest := string(data[:nul]), data[nul+1:]
+
+	switch chunkType {
+	case "tEXt":
+		return keyword, string(rest), true
+	case "zTXt":
+		if len(rest) < 1 {
+			return "", "", false
+		}
+		text, err := inflate(rest[1:])
+		return keyword, string(text), err == nil
+	case "iTXt":
+		if len(rest) < 2 {
+			return "", "", false
+		}
+		compressed := rest[0] == 1
+		rest = rest[2:]
+		// Skip the language tag and the translated keyword.
+		for i := 0; i < 2; i++ {
+			nul := bytes.IndexByte(rest, 0)
+			if nul < 0 {
+				return "", "", false
+			}
+			rest = rest[nul+1:]
+		}
+		if !compressed {
+			return keyword, string(rest), true
+		}
+		text, err := inflate(rest)
+		return keyword, string(text), err == nil
+	}
+	return "", "", false
+}
+
+// inflate decompresses zlib data, bounded by maxPNGTextChunk.
+func inflate(data []byte) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(io.LimitReader(zr, maxPNGTextChunk))
+}
+
+// parsePNGCreationTime parses the free-form "Creation Time" keyword value.
+func parsePNGCreationTime(s string) (time.Time, *time.Location, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range pngCreationTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, t.Location(), true
+		}
+	}
+	if t, loc, ok := parseXMPTime(s); ok {
+		return t, loc, true
+	}
+	return time.Time{}, nil, false
+}
+
+// mergeCameraInfo copies camera fields from src into dst where dst has none yet.
+func mergeCameraInfo(dst, src *MediaInfo) {
+	if dst.Make == "" {
+		dst.Make = src.Make
+	}
+	if dst.Model == "" {
+		dst.Model = src.Model
+	}
+	if dst.Serial == "" {
+		dst.Serial = src.Serial
+	}
+	if dst.Lens == "" {
+		dst.Lens = src.Lens
+	}
+	if dst.Width == 0 {
+		dst.Width, dst.Height = src.Width, src.Height
+	}
+	if dst.GPS == nil {
+		dst.GPS = src.GPS
+	}
+}
@@ -0,0 +1,122 @@
+package organizer
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testPNGChunk builds one PNG chunk with a valid CRC.
+func testPNGChunk(chunkType string, data []byte) []byte {
+	chunk := make([]byte, 8, 12+len(data))
+	binary.BigEndian.PutUint32(chunk[0:4], uint32(len(data)))
+	copy(chunk[4:8], chunkType)
+	chunk = append(chunk, data...)
+	crc := crc32.ChecksumIEEE(chunk[4:])
+	return binary.BigEndian.AppendUint32(chunk, crc)
+}
+
+// testPNG builds a PNG file from metadata chunks, wrapped in IHDR, IDAT and IEND.
+func testPNG(chunks ...[]byte) []byte {
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:4], 640)
+	binary.BigEndian.PutUint32(ihdr[4:8], 480)
+	parts := [][]byte{[]byte(pngSignature), testPNGChunk("IHDR", ihdr)}
+	parts = append(parts, chunks...)
+	parts = append(parts, testPNGChunk("IDAT", make([]byte, 16)), testPNGChunk("IEND", nil))
+	return bytes.Join(parts, nil)
+}
+
+func testXMPPacket(body string) string {
+	return `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF><rdf:Description ` + body + `</rdf:Description></rdf:RDF></x:xmpmeta>`
+}
+
+func TestExtractPNGExifChunk(t *testing.T) {
+	want, err := mediaInfoFromExif(testExifBlock(t))
+	if err != nil {
+		t.Fatalf("Failed to read sample EXIF: %v", err)
+	}
+
+	info, err := extractPNG(bytes.NewReader(testPNG(testPNGChunk("eXIf", testExifBlock(t)))))
+	if err != nil {
+		t.Fatalf("extractPNG returned error: %v", err)
+	}
+	if !info.CaptureTime.Equal(want.CaptureTime) || info.DateSource != DateSourceExifOriginal {
+		t.Errorf("Expected %s from %s, got %s from %s", want.CaptureTime, DateSourceExifOriginal, info.CaptureTime, info.DateSource)
+	}
+	if info.Width != 640 || info.Height != 480 {
+		t.Errorf("Expected IHDR size 640x480, got %dx%d", info.Width, info.Height)
+	}
+}
+
+func TestExtractPNGCreationTime(t *testing.T) {
+	text := testPNGChunk("tEXt", []byte("Creation Time\x00Mon, 01 May 2023 14:30:00 +0200"))
+	info, err := extractPNG(bytes.NewReader(testPNG(text)))
+	if err != nil {
+		t.Fatalf("extractPNG returned error: %v", err)
+	}
+	want := time.Date(2023, 5, 1, 12, 30, 0, 0, time.UTC)
+	if !info.CaptureTime.Equal(want) || info.DateSource != DateSourcePNGCreationTime {
+		t.Errorf("Expected %s from %s, got %s from %s", want, DateSourcePNGCreationTime, info.CaptureTime, info.DateSource)
+	}
+}
+
+func TestExtractPNGCompressedCreationTime(t *testing.T) {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write([]byte("2023-05-01T14:30:00"))
+	zw.Close()
+
+	text := testPNGChunk("zTXt", append([]byte("Creation Time\x00\x00"), compressed.Bytes()...))
+	info, err := extractPNG(bytes.NewReader(testPNG(text)))
+	if err != nil {
+		t.Fatalf("extractPNG returned error: %v", err)
+	}
+	if got := info.CaptureTime.Format("2006-01-02 15:04:05"); got != "2023-05-01 14:30:00" {
+		t.Errorf("Expected 2023-05-01 14:30:00, got %s", got)
+	}
+}
+
+func TestExtractPNGPriority(t *testing.T) {
+	// eXIf only carries DateTime, so the XMP DateTimeOriginal must win over it
+	// and over the text Creation Time, regardless of chunk order.
+	exifDateTime := testTIFF(binary.BigEndian, []testTIFFTag{testASCII(tiffTagDateTime, "2024:01:01 00:00:00")}, nil, nil)
+	xmp := testXMPPacket(`exif:DateTimeOriginal="2023-05-01T14:30:00+02:00">`)
+
+	info, err := extractPNG(bytes.NewReader(testPNG(
+		testPNGChunk("tEXt", []byte("Creation Time\x002024-02-02T10:00:00")),
+		testPNGChunk("eXIf", exifDateTime),
+		testPNGChunk("iTXt", append([]byte("XML:com.adobe.xmp\x00\x00\x00\x00\x00"), xmp...)),
+	)))
+	if err != nil {
+		t.Fatalf("extractPNG returned error: %v", err)
+	}
+	if info.DateSource != DateSourceXMPOriginal {
+		t.Errorf("Expected date source %s, got %s", DateSourceXMPOriginal, info.DateSource)
+	}
+	if info.Timezone == nil {
+		t.Errorf("Expected the XMP offset to be kept")
+	}
+}
+
+func TestExtractPNGWithoutDate(t *testing.T) {
+	if _, err := extractPNG(bytes.NewReader(testPNG())); err == nil {
+		t.Errorf("Expected an error for a PNG without dates")
+	}
+}
+
+func TestRegistryExtractPNGFile(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "Screenshot.png")
+	text := testPNGChunk("tEXt", []byte("Creation Time\x002023-05-01T14:30:00"))
+	if err := os.WriteFile(filePath, testPNG(text), 0644); err != nil {
+		t.Fatalf("Failed to write PNG: %v", err)
+	}
+	if _, err := DefaultRegistry.Extract(filePath); err != nil {
+		t.Errorf("Extract returned error: %v", err)
+	}
+}
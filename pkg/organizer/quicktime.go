@@ -0,0 +1,285 @@
+package organizer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- QuickTime / MP4 / MOV / M4V / 3GP capture dates ---
+
+// Date sources recognised by the QuickTime extractor.
+const (
+	DateSourceQuickTimeCreationDate DateSource = "quicktime:CreationDate"
+	DateSourceQuickTimeDay          DateSource = "quicktime:Day"
+	DateSourceQuickTimeMovieHeader  DateSource = "quicktime:mvhd"
+	DateSourceQuickTimeTrackHeader  DateSource = "quicktime:tkhd"
+)
+
+// quickTimeEpoch is the origin of the creation times stored in mvhd and tkhd boxes.
+var quickTimeEpoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// quickTimeDateLayouts lists the formats used by Apple creationdate and ©day values.
+var quickTimeDateLayouts = []string{
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02T15:04:05-07:00",
+	"2006-01-02T15:04:05Z",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// iso6709Pattern matches ISO 6709 locations such as "+37.3349-122.0090+010.000/".
+var iso6709Pattern = regexp.MustCompile(`^([+-]\d+(?:\.\d+)?)([+-]\d+(?:\.\d+)?)([+-]\d+(?:\.\d+)?)?`)
+
+// extractQuickTime reads capture metadata from an ISO-BMFF or QuickTime movie.
+func extractQuickTime(r io.ReadSeeker) (*MediaInfo, error) {
+	moov, err := findBMFFBox(r, "moov")
+	if err != nil {
+		return nil, ErrNoCaptureDate
+	}
+	children, err := bmffChildren(r, moov)
+	if err != nil && len(children) == 0 {
+		return nil, err
+	}
+
+	info := &MediaInfo{}
+	var candidates []DateCandidate
+	var headerTime, trackTime time.Time
+
+	for _, box := range children {
+		switch box.Type {
+		case "mvhd":
+			data, err := readBoxData(r, box)
+			if err == nil {
+				headerTime = quickTimeHeaderTime(data)
+			}
+		case "trak":
+			tkhd, err := findBMFFPath(r, []bmffBox{box}, "trak", "tkhd")
+			if err != nil {
+				continue
+			}
+			data, err := readBoxData(r, tkhd)
+			if err != nil {
+				continue
+			}
+			if trackTime.IsZero() {
+				trackTime = quickTimeHeaderTime(data)
+			}
+			if width, height := quickTimeTrackSize(data); width > info.Width {
+				info.Width, info.Height = width, height
+			}
+		case "meta":
+			candidates = append(candidates, readQuickTimeKeys(r, box, info)...)
+		case "udta":
+			candidates = append(candidates, readQuickTimeUserData(r, box, info)...)
+		}
+	}
+
+	// Apple creationdate and ©day carry the local offset, so they come before the UTC header times.
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Source == DateSourceQuickTimeCreationDate && candidates[j].Source != DateSourceQuickTimeCreationDate
+	})
+	if !headerTime.IsZero() {
+		candidates = append(candidates, DateCandidate{Time: headerTime.In(time.Local), Instant: true, Source: DateSourceQuickTimeMovieHeader})
+	}
+	if !trackTime.IsZero() {
+		candidates = append(candidates, DateCandidate{Time: trackTime.In(time.Local), Instant: true, Source: DateSourceQuickTimeTrackHeader})
+	}
+	return info, info.setDates(candidates)
+}
+
+// quickTimeHeaderTime returns the creation time of an mvhd or tkhd payload.
+// The value counts seconds since 1904-01-01 UTC; zero means it was never set.
+func quickTimeHeaderTime(data []byte) time.Time {
+	if len(data) < 8 {
+		return time.Time{}
+	}
+	var seconds uint64
+	if data[0] == 1 {
+		if len(data) < 12 {
+			return time.Time{}
+		}
+		seconds = binary.BigEndian.Uint64(data[4:12])
+	} else {
+		seconds = uint64(binary.BigEndian.Uint32(data[4:8]))
+	}
+	if seconds == 0 {
+		return time.Time{}
+	}
+	return quickTimeEpoch.Add(time.Duration(seconds) * time.Second)
+}
+
+// quickTimeTrackSize returns the presentation size stored at the end of a tkhd payload.
+func quickTimeTrackSize(data []byte) (int, int) {
+	offset := 76
+	if len(data) > 0 && data[0] == 1 {
+		offset = 88
+	}
+	if len(data) < offset+8 {
+		return 0, 0
+	}
+	width := binary.BigEndian.Uint32(data[offset:offset+4]) >> 16
+	height := binary.BigEndian.Uint32(data[offset+4:offset+8]) >> 16
+	return int(width), int(height)
+}
+
+// parseQuickTimeDate parses a creationdate or ©day string, keeping its offset when present.
+func parseQuickTimeDate(s string) (time.Time, *time.Location, bool) {
+	s = strings.TrimSpace(strings.TrimRight(s, "\x00"))
+	for _, layout := range quickTimeDateLayouts {
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			continue
+		}
+		if strings.Contains(layout, "07") || strings.HasSuffix(layout, "Z") {
+			return t, t.Location(), true
+		}
+		return t, nil, true
+	}
+	return time.Time{}, nil, false
+}
+
+// readQuickTimeKeys reads the Apple "mdta" metadata (keys + ilst) of a meta box.
+func readQuickTimeKeys(r io.ReadSeeker, meta bmffBox, info *MediaInfo) []DateCandidate {
+	children, err := bmffChildren(r, meta)
+	if err != nil && len(children) == 0 {
+		return nil
+	}
+
+	var keys []string
+	var items []bmffBox
+	for _, box := range children {
+		switch box.Type {
+		case "keys":
+			data, err := readBoxData(r, box)
+			if err != nil || len(data) < 8 {
+				return nil
+			}
+			count := int(binary.BigEndian.Uint32(data[4:8]))
+			for pos := 8; count > 0 && pos+8 <= len(data); count-- {
+				size := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+				if size < 8 || pos+size > len(data) {
+					break
+				}
+				keys = append(keys, string(data[pos+8:pos+size]))
+				pos += size
+			}
+		case "ilst":
+			items, _ = bmffChildren(r, box)
+		}
+	}
+
+	var candidates []DateCandidate
+	for _, item := range items {
+		index := int(binary.BigEndian.Uint32([]byte(item.Type))) - 1
+		if index < 0 || index >= len(keys) {
+			continue
+		}
+		value, ok := quickTimeItemValue(r, item)
+		if !ok {
+			continue
+		}
+
+		switch keys[index] {
+		case "com.apple.quicktime.creationdate":
+			if t, loc, ok := parseQuickTimeDate(value); ok {
+				candidates = append(candidates, zonedCandidate(t, loc, DateSourceQuickTimeCreationDate))
+			}
+		case "com.apple.quicktime.make":
+			info.Make = value
+		case "com.apple.quicktime.model":
+			info.Model = value
+		case "com.apple.quicktime.location.ISO6709":
+			info.GPS = parseISO6709(value)
+		}
+	}
+	return candidates
+}
+
+// quickTimeItemValue returns the text stored in the "data" box of an ilst item.
+func quickTimeItemValue(r io.ReadSeeker, item bmffBox) (string, bool) {
+	dataBox, err := findBMFFPath(r, []bmffBox{item}, item.Type, "data")
+	if err != nil {
+		return "", false
+	}
+	data, err := readBoxData(r, dataBox)
+	if err != nil || len(data) < 8 {
+		return "", false
+	}
+	// Type indicator (4 bytes) and locale (4 bytes) precede the value.
+	return strings.TrimSpace(string(data[8:])), true
+}
+
+// readQuickTimeUserData reads the classic ©day, ©mak, ©mod and ©xyz atoms of a udta box.
+func readQuickTimeUserData(r io.ReadSeeker, udta bmffBox, info *MediaInfo) []DateCandidate {
+	children, err := bmffChildren(r, udta)
+	if err != nil && len(children) == 0 {
+		return nil
+	}
+
+	var candidates []DateCandidate
+	for _, box := range children {
+		if box.Type == "meta" {
+			candidates = append(candidates, readQuickTimeKeys(r, box, info)...)
+			continue
+		}
+		if !strings.HasPrefix(box.Type, "\xa9") {
+			continue
+		}
+
+		data, err := readBoxData(r, box)
+		if err != nil || len(data) < 4 {
+			continue
+		}
+		// Classic user data strings start with a 2-byte length and a 2-byte language code.
+		value := data[4:]
+		if size := int(binary.BigEndian.Uint16(data[0:2])); size <= len(value) {
+			value = value[:size]
+		}
+		text := strings.TrimSpace(string(bytes.TrimRight(value, "\x00")))
+
+		switch box.Type {
+		case "\xa9day":
+			if t, loc, ok := parseQuickTimeDate(text); ok {
+				candidates = append(candidates, zonedCandidate(t, loc, DateSourceQuickTimeDay))
+			}
+		case "\xa9mak":
+			if info.Make == "" {
+				info.Make = text
+			}
+		case "\xa9mod":
+			if info.Model == "" {
+				info.Model = text
+			}
+		case "\xa9xyz":
+			if info.GPS == nil {
+				info.GPS = parseISO6709(text)
+			}
+		}
+	}
+	return candidates
+}
+
+// parseISO6709 parses an ISO 6709 location string.
+func parseISO6709(s string) *GPSInfo {
+	m := iso6709Pattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return nil
+	}
+	lat, err1 := strconv.ParseFloat(m[1], 64)
+	lon, err2 := strconv.ParseFloat(m[2], 64)
+	if err1 != nil || err2 != nil {
+		return nil
+	}
+	gps := &GPSInfo{Latitude: lat, Longitude: lon}
+	if m[3] != "" {
+		gps.Altitude, _ = strconv.ParseFloat(m[3], 64)
+	}
+	return gps
+}
@@ -0,0 +1,172 @@
+package organizer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testBox builds an ISO-BMFF box from a type and payload parts.
+func testBox(boxType string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	box := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(box[0:4], uint32(8+len(body)))
+	copy(box[4:8], boxType)
+	return append(box, body...)
+}
+
+// testMovieHeader builds a version 0 mvhd/tkhd style payload with the given creation time.
+func testMovieHeader(created time.Time, size int) []byte {
+	payload := make([]byte, size)
+	seconds := uint32(created.Sub(quickTimeEpoch) / time.Second)
+	binary.BigEndian.PutUint32(payload[4:8], seconds)
+	return payload
+}
+
+// testAppleCreationDate builds an Apple mdta meta box holding a creationdate key.
+func testAppleCreationDate(value string) []byte {
+	key := "com.apple.quicktime.creationdate"
+	keyEntry := make([]byte, 8)
+	binary.BigEndian.PutUint32(keyEntry[0:4], uint32(8+len(key)))
+	copy(keyEntry[4:8], "mdta")
+	keys := testBox("keys", []byte{0, 0, 0, 0, 0, 0, 0, 1}, keyEntry, []byte(key))
+
+	data := testBox("data", []byte{0, 0, 0, 1, 0, 0, 0, 0}, []byte(value))
+	item := testBox("\x00\x00\x00\x01", data)
+	hdlr := testBox("hdlr", make([]byte, 8), []byte("mdta"), make([]byte, 12))
+	return testBox("meta", hdlr, keys, testBox("ilst", item))
+}
+
+func testMovie(moovChildren ...[]byte) []byte {
+	ftyp := testBox("ftyp", []byte("qt  "), make([]byte, 4), []byte("qt  "))
+	mdat := testBox("mdat", make([]byte, 64))
+	return bytes.Join([][]byte{ftyp, mdat, testBox("moov", moovChildren...)}, nil)
+}
+
+func TestExtractQuickTimeMovieHeader(t *testing.T) {
+	created := time.Date(2023, 5, 1, 22, 30, 0, 0, time.UTC)
+	movie := testMovie(testBox("mvhd", testMovieHeader(created, 100)))
+
+	info, err := extractQuickTime(bytes.NewReader(movie))
+	if err != nil {
+		t.Fatalf("extractQuickTime returned error: %v", err)
+	}
+	if !info.CaptureTime.Equal(created) {
+		t.Errorf("Expected capture time %s, got %s", created, info.CaptureTime)
+	}
+	if info.DateSource != DateSourceQuickTimeMovieHeader {
+		t.Errorf("Expected date source %s, got %s", DateSourceQuickTimeMovieHeader, info.DateSource)
+	}
+}
+
+func TestExtractQuickTimeMovieHeaderVersion1(t *testing.T) {
+	created := time.Date(2040, 2, 3, 4, 5, 6, 0, time.UTC)
+	payload := make([]byte, 112)
+	payload[0] = 1
+	binary.BigEndian.PutUint64(payload[4:12], uint64(created.Sub(quickTimeEpoch)/time.Second))
+	movie := testMovie(testBox("mvhd", payload))
+
+	info, err := extractQuickTime(bytes.NewReader(movie))
+	if err != nil {
+		t.Fatalf("extractQuickTime returned error: %v", err)
+	}
+	if !info.CaptureTime.Equal(created) {
+		t.Errorf("Expected capture time %s, got %s", created, info.CaptureTime)
+	}
+}
+
+func TestExtractQuickTimeAppleCreationDate(t *testing.T) {
+	created := time.Date(2023, 5, 1, 22, 30, 0, 0, time.UTC)
+	movie := testMovie(
+		testBox("mvhd", testMovieHeader(created, 100)),
+		testAppleCreationDate("2023-05-02T00:30:00+0200"),
+	)
+
+	info, err := extractQuickTime(bytes.NewReader(movie))
+	if err != nil {
+		t.Fatalf("extractQuickTime returned error: %v", err)
+	}
+	if info.DateSource != DateSourceQuickTimeCreationDate {
+		t.Errorf("Expected date source %s, got %s", DateSourceQuickTimeCreationDate, info.DateSource)
+	}
+	if !info.CaptureTime.Equal(created) {
+		t.Errorf("Expected capture instant %s, got %s", created, info.CaptureTime)
+	}
+	if got := info.CaptureTime.Format("20060102"); got != "20230502" {
+		t.Errorf("Expected local capture day 20230502, got %s", got)
+	}
+	if info.Timezone == nil {
+		t.Errorf("Expected the creationdate offset to be kept")
+	}
+}
+
+func TestExtractQuickTimeTrackHeaderFallback(t *testing.T) {
+	created := time.Date(2021, 7, 4, 12, 0, 0, 0, time.UTC)
+	tkhd := testMovieHeader(created, 84)
+	binary.BigEndian.PutUint32(tkhd[76:80], 1920<<16)
+	binary.BigEndian.PutUint32(tkhd[80:84], 1080<<16)
+	movie := testMovie(
+		testBox("mvhd", make([]byte, 100)),
+		testBox("trak", testBox("tkhd", tkhd)),
+	)
+
+	info, err := extractQuickTime(bytes.NewReader(movie))
+	if err != nil {
+		t.Fatalf("extractQuickTime returned error: %v", err)
+	}
+	if info.DateSource != DateSourceQuickTimeTrackHeader || !info.CaptureTime.Equal(created) {
+		t.Errorf("Expected tkhd time %s, got %s from %s", created, info.CaptureTime, info.DateSource)
+	}
+	if info.Width != 1920 || info.Height != 1080 {
+		t.Errorf("Expected 1920x1080, got %dx%d", info.Width, info.Height)
+	}
+}
+
+func TestExtractQuickTimeNoDate(t *testing.T) {
+	movie := testMovie(testBox("mvhd", make([]byte, 100)))
+	if _, err := extractQuickTime(bytes.NewReader(movie)); err == nil {
+		t.Errorf("Expected an error for a movie without creation time")
+	}
+}
+
+func TestReadBMFFBoxesLargeSize(t *testing.T) {
+	// A box whose 64-bit size would wrap the end offset around.
+	huge := make([]byte, 16)
+	binary.BigEndian.PutUint32(huge[0:4], 1)
+	copy(huge[4:8], "free")
+	binary.BigEndian.PutUint64(huge[8:16], 0x7fffffffffffffff)
+	data := append(testBox("ftyp", []byte("qt  ")), huge...)
+
+	boxes, err := readBMFFBoxes(bytes.NewReader(data), 0, int64(len(data)))
+	if err == nil || len(boxes) != 1 || boxes[0].Type != "ftyp" {
+		t.Errorf("Expected the oversized box to be rejected, got %+v (%v)", boxes, err)
+	}
+}
+
+func TestRegistryExtractVideoFiles(t *testing.T) {
+	created := time.Date(2022, 8, 9, 10, 11, 12, 0, time.UTC)
+	movie := testMovie(testBox("mvhd", testMovieHeader(created, 100)))
+
+	dir := t.TempDir()
+	for _, name := range []string{"clip.MOV", "clip.mp4", "clip.m4v", "clip.3gp"} {
+		filePath := filepath.Join(dir, name)
+		if err := os.WriteFile(filePath, movie, 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		info, err := DefaultRegistry.Extract(filePath)
+		if err != nil {
+			t.Errorf("Extract(%s) returned error: %v", name, err)
+			continue
+		}
+		if !info.CaptureTime.Equal(created) {
+			t.Errorf("Extract(%s) expected %s, got %s", name, created, info.CaptureTime)
+		}
+	}
+
+	if got := sniffContentType(movie[:32]); got != "video/quicktime" {
+		t.Errorf("Expected video/quicktime from ftyp brand, got %s", got)
+	}
+}
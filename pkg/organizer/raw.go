@@ -0,0 +1,94 @@
+package organizer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// --- Camera RAW capture dates ---
+
+// rawFormats maps the TIFF-based RAW content types to their file extensions.
+var rawFormats = []struct {
+	contentType string
+	extensions  []string
+}{
+	{"image/x-sony-arw", []string{".arw", ".srf", ".sr2"}},
+	{"image/x-nikon-nef", []string{".nef", ".nrw"}},
+	{"image/x-canon-cr2", []string{".cr2"}},
+	{"image/x-adobe-dng", []string{".dng"}},
+	{"image/x-olympus-orf", []string{".orf", ".ori"}},
+	{"image/x-panasonic-rw2", []string{".rw2", ".rwl"}},
+	{"image/x-pentax-pef", []string{".pef"}},
+	{"image/x-samsung-srw", []string{".srw"}},
+	{"image/x-epson-erf", []string{".erf"}},
+	{"image/x-hasselblad-3fr", []string{".3fr", ".fff"}},
+	{"image/x-phaseone-iiq", []string{".iiq"}},
+	{"image/x-mamiya-mef", []string{".mef"}},
+	{"image/x-leaf-mos", []string{".mos"}},
+	{"image/x-kodak-dcr", []string{".dcr", ".kdc"}},
+}
+
+// rafMagic starts every Fujifilm RAF file.
+const rafMagic = "FUJIFILMCCD-RAW "
+
+// errNoEmbeddedExif is returned when an embedded JPEG has no Exif segment.
+var errNoEmbeddedExif = errors.New("no Exif segment in embedded JPEG")
+
+// extractRAF reads capture metadata from the Exif segment of the JPEG preview embedded in a Fujifilm RAF file.
+func extractRAF(r io.ReadSeeker) (*MediaInfo, error) {
+	header := make([]byte, 92)
+	if _, err := io.ReadFull(r, header); err != nil || string(header[:16]) != rafMagic {
+		return nil, ErrNoCaptureDate
+	}
+
+	jpegOffset := int64(binary.BigEndian.Uint32(header[84:88]))
+	tiffOffset, err := jpegExifOffset(r, jpegOffset)
+	if err != nil {
+		return nil, ErrNoCaptureDate
+	}
+	return mediaInfoFromTIFF(r, tiffOffset)
+}
+
+// jpegExifOffset walks the markers of the JPEG at offset and returns
+// the offset of the TIFF header inside its APP1 Exif segment.
+func jpegExifOffset(r io.ReadSeeker, offset int64) (int64, error) {
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	marker := make([]byte, 4)
+	if _, err := io.ReadFull(r, marker[:2]); err != nil || marker[0] != 0xFF || marker[1] != 0xD8 {
+		return 0, errNoEmbeddedExif
+	}
+	pos := offset + 2
+
+	for {
+		if _, err := io.ReadFull(r, marker); err != nil {
+			return 0, errNoEmbeddedExif
+		}
+		if marker[0] != 0xFF {
+			return 0, errNoEmbeddedExif
+		}
+		// Start of scan or end of image: no more metadata segments.
+		if marker[1] == 0xDA || marker[1] == 0xD9 {
+			return 0, errNoEmbeddedExif
+		}
+
+		length := int64(binary.BigEndian.Uint16(marker[2:4]))
+		if marker[1] == 0xE1 && length >= 8 {
+			signature := make([]byte, 6)
+			if _, err := io.ReadFull(r, signature); err != nil {
+				return 0, errNoEmbeddedExif
+			}
+			if bytes.Equal(signature, []byte("Exif\x00\x00")) {
+				return pos + 4 + 6, nil
+			}
+		}
+
+		pos += 2 + length
+		if _, err := r.Seek(pos, io.SeekStart); err != nil {
+			return 0, err
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package organizer
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --- Renaming files with a template ---
+
+// RenamedFile is a file the rename template gave a new name.
+type RenamedFile struct {
+	Path    string // original path
+	NewPath string
+}
+
+// pendingRename is a file waiting for its per-day sequence number.
+type pendingRename struct {
+	path string
+	day  string
+	time time.Time // wall clock of the bucketing time, including sub-seconds
+	info *MediaInfo
+	seq  int
+}
+
+// ParseRenameFormat parses a rename template such as "{date:20060102_150405}_{camera}_{seq:04}{ext}".
+// In rename templates {ext} includes the dot; when the template has no {ext}, the original
+// extension is appended. {seq} numbers the files of each day in the order they were taken,
+// counting the files of the run only: a later run into the same library starts from 1 again.
+func ParseRenameFormat(format string) (*Template, error) {
+	tmpl, err := ParseTemplate(format)
+	if err != nil {
+		return nil, err
+	}
+	for _, segment := range tmpl.segments {
+		if strings.ContainsAny(segment.literal, `/\`) {
+			return nil, fmt.Errorf("rename template %q must not contain path separators", format)
+		}
+	}
+	return tmpl, nil
+}
+
+// renameTemplate returns the parsed RenameFormat, or nil when files keep their names. An invalid
+// template keeps the names too.
+func (o *Organizer) renameTemplate() *Template {
+	if o.RenameFormat == "" {
+		return nil
+	}
+	if o.renameTmpl != nil && o.renameTmpl.source == o.RenameFormat {
+		return o.renameTmpl
+	}
+	tmpl, err := ParseRenameFormat(o.RenameFormat)
+	if err != nil {
+		log.Printf("Invalid rename format, keeping file names: %v", err)
+		tmpl = &Template{}
+	}
+	tmpl.source = o.RenameFormat
+	o.renameTmpl = tmpl
+	return tmpl
+}
+
+// addPendingRename remembers a file so it can be numbered once all files of its day are known.
+func (o *Organizer) addPendingRename(filePath string, info *MediaInfo) {
+	if o.renameTemplate() == nil {
+		return
+	}
+	t := o.bucketTime(info)
+	o.pending = append(o.pending, pendingRename{path: filePath, day: t.Format("2006-01-02"), time: wallClock(t), info: info})
+	o.sequences = nil
+}
+
+// sequence returns the per-day sequence number of a file, counting from 1. Files are numbered
+// by capture time, so the sub-seconds of a burst keep its frames in order; files taken in the
+// same instant are numbered by path.
+func (o *Organizer) sequence(filePath string) int {
+	p, _ := o.numbered(filePath)
+	return p.seq
+}
+
+// numbered returns a pending file with its sequence number, numbering all files on first use.
+func (o *Organizer) numbered(filePath string) (pendingRename, bool) {
+	if o.sequences == nil {
+		sorted := append([]pendingRename(nil), o.pending...)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			a, b := sorted[i], sorted[j]
+			switch {
+			case a.day != b.day:
+				return a.day < b.day
+			case !a.time.Equal(b.time):
+				return a.time.Before(b.time)
+			}
+			return a.path < b.path
+		})
+
+		o.sequences = make(map[string]pendingRename, len(sorted))
+		counters := make(map[string]int)
+		for _, p := range sorted {
+			counters[p.day]++
+			p.seq = counters[p.day]
+			o.sequences[p.path] = p
+		}
+	}
+	p, ok := o.sequences[filePath]
+	return p, ok
+}
+
+// fileName returns the name a file gets in its date folder.
+func (o *Organizer) fileName(filePath string, info *MediaInfo) string {
+	base := filepath.Base(filePath)
+	tmpl := o.renameTemplate()
+	if tmpl == nil {
+		return base
+	}
+
+	data := templateData{
+		time:     o.bucketTime(info),
+		info:     info,
+		filePath: filePath,
+		locale:   o.locale(),
+		seq:      o.sequence(filePath),
+		fileName: true,
+	}
+	name := sanitizePathComponent(tmpl.render(data))
+	if name == "" {
+		return base
+	}
+	if !tmpl.uses("ext") {
+		name += filepath.Ext(base)
+	}
+	return name
+}
+
+// renameFileEntries gives the entries collected by AddFileEntries their new names.
+func (o *Organizer) renameFileEntries() {
+	if o.renameTemplate() == nil {
+		return
+	}
+	infos := make(map[string]*MediaInfo, len(o.pending))
+	for _, p := range o.pending {
+		infos[p.path] = p.info
+	}
+	for i, entry := range o.fEntries {
+		info, ok := infos[entry.Path]
+		if !ok {
+			continue
+		}
+		o.fEntries[i].NewPath = filepath.Join(filepath.Dir(entry.NewPath), o.fileName(entry.Path, info))
+	}
+}
+
+// recordRename remembers a file that was placed under a new name for the end-of-run report. It
+// is called once the file is in place, with the name it got after collisions were resolved.
+func (o *Organizer) recordRename(filePath, newPath string) {
+	if filepath.Base(filePath) == filepath.Base(newPath) {
+		return
+	}
+	o.statsMu.Lock()
+	defer o.statsMu.Unlock()
+	o.renames = append(o.renames, RenamedFile{Path: filePath, NewPath: newPath})
+}
+
+// Renames returns the files given a new name in the current run, with their original paths.
+func (o *Organizer) Renames() []RenamedFile {
+	return o.renames
+}
+
+// printRenames reports the original name of every renamed file.
+func (o *Organizer) printRenames() {
+	if len(o.renames) == 0 {
+		return
+	}
+	fmt.Printf("Renamed %d files:\n", len(o.renames))
+	for _, r := range o.renames {
+		fmt.Printf("  %s -> %s\n", r.Path, r.NewPath)
+	}
+}
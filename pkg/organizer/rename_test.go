@@ -0,0 +1,115 @@
+package organizer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenameSequence(t *testing.T) {
+	for _, run := range []bool{true, false} {
+		dir := t.TempDir()
+		// A burst shot within one second and a file of the next day.
+		for _, name := range []string{"PXL_20230501_143000123.XYZ", "PXL_20230501_143000045.XYZ", "IMG_20230502_090000.xyz"} {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+		}
+
+		org := NewOrganizer(dir, "ymd", "generated", "seq", "0", "copy")
+		org.Extractors = testChainRegistry()
+		org.RenameFormat = "{date:20060102_150405}_{seq:03}{ext:lower}"
+		if run {
+			org.Run(0)
+		} else {
+			org.AddFileEntries(dir)
+			org.OrganizeFiles(0)
+			if got := len(org.Renames()); got != 3 {
+				t.Errorf("Expected 3 renamed files in the report, got %d", got)
+			}
+		}
+
+		for _, name := range []string{
+			"20230501/20230501_143000_001.xyz",
+			"20230501/20230501_143000_002.xyz",
+			"20230502/20230502_090000_001.xyz",
+		} {
+			if _, err := os.Stat(filepath.Join(dir, "generated", filepath.FromSlash(name))); err != nil {
+				t.Errorf("Expected %s: %v", name, err)
+			}
+		}
+
+		// The burst frames keep their order.
+		got, err := os.ReadFile(filepath.Join(dir, "generated", "20230501", "20230501_143000_001.xyz"))
+		if err != nil || string(got) != "PXL_20230501_143000045.XYZ" {
+			t.Errorf("Expected the earlier frame first, got %q (%v)", got, err)
+		}
+	}
+}
+
+func TestRenameFormat(t *testing.T) {
+	org := NewOrganizer("", "ymd", "generated", "seq", "0", "copy")
+	info := &MediaInfo{CaptureTime: time.Date(2023, 5, 1, 14, 30, 0, 0, time.UTC)}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"", "IMG_20230501_143000.JPG"},
+		{"{name}_{camera}", "IMG_20230501_143000_Unknown.JPG"},
+		{"{date:2006-01-02 15:04:05}{ext:upper}", "2023-05-01 14_30_00.JPG"},
+		{"{year", "IMG_20230501_143000.JPG"},
+	}
+	for _, tt := range tests {
+		org.RenameFormat = tt.format
+		if got := org.fileName("/photos/IMG_20230501_143000.JPG", info); got != tt.want {
+			t.Errorf("Rename format %q gave %q, want %q", tt.format, got, tt.want)
+		}
+	}
+
+	if _, err := ParseRenameFormat("{year}/{seq}"); err == nil {
+		t.Error("Expected error for a rename template with a path separator")
+	}
+	if _, err := ParseFolderFormat("{year}/{seq}"); err == nil {
+		t.Error("Expected error for {seq} in a folder template")
+	}
+}
+
+func TestRenamesReportFinalName(t *testing.T) {
+	src, library := t.TempDir(), t.TempDir()
+	writeTestFiles(t, src, "IMG_20230501_143000.xyz", "IMG_20230502_090000.xyz")
+	// Both new names are taken in the library.
+	for _, name := range []string{"20230501/day_001.xyz", "20230502/day_001.xyz"} {
+		dst := filepath.Join(library, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(dst, []byte("library"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, policy := range []string{CollisionRename, CollisionSkip} {
+		org := NewOrganizer(src, "ymd", "generated", "seq", "0", "copy")
+		org.Extractors = testChainRegistry()
+		org.OutputRoot = library
+		org.RenameFormat = "day_{seq:03}{ext}"
+		org.CollisionPolicy = policy
+		org.AddFileEntries(src)
+		org.OrganizeFiles(0)
+
+		renames := org.Renames()
+		switch policy {
+		case CollisionRename:
+			if len(renames) != 2 || !strings.HasSuffix(renames[0].NewPath, "_1.xyz") || !strings.HasSuffix(renames[1].NewPath, "_1.xyz") {
+				t.Errorf("Expected the names the files got in the library, got %+v", renames)
+			}
+		case CollisionSkip:
+			if len(renames) != 0 {
+				t.Errorf("Expected skipped files to be left out of the report, got %+v", renames)
+			}
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package organizer
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// --- Multiple source folders ---
+
+// SourceSummary counts what happened to the files of one source folder.
+type SourceSummary struct {
+	Source     string
+	Files      int // files with a capture date
+	Grouped    int // files copied or moved into the library
+	Skipped    int // files left in place by the collision policy
+	Duplicates int // files handled as duplicates of files already in the library
+	Failed     int // files that could not be copied or moved
+}
+
+// sources returns SrcPath and the further Sources, leaving out any folder inside another one so
+// no file is organized twice.
+func (o *Organizer) sources() []string {
+	var candidates []string
+	for _, src := range append([]string{o.SrcPath}, o.Sources...) {
+		if src != "" {
+			candidates = append(candidates, src)
+		}
+	}
+
+	var sources []string
+	for i, src := range candidates {
+		inside := false
+		for j, other := range candidates {
+			// Of two equal folders the first one is kept.
+			if i != j && isInside(src, other) && (!samePath(src, other) || j < i) {
+				inside = true
+				break
+			}
+		}
+		if inside {
+			log.Printf("Skipping source %s: it is inside another source", src)
+			continue
+		}
+		sources = append(sources, src)
+	}
+	return sources
+}
+
+// isInside reports whether dir is parent or a folder below it.
+func isInside(dir, parent string) bool {
+	absDir, errDir := filepath.Abs(dir)
+	absParent, errParent := filepath.Abs(parent)
+	if errDir != nil || errParent != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absParent, absDir)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// sourceOf returns the source folder a file was found in.
+func (o *Organizer) sourceOf(filePath string) string {
+	for _, src := range append([]string{o.SrcPath}, o.Sources...) {
+		if src != "" && strings.HasPrefix(filePath, strings.TrimSuffix(path.Clean(src), "/")+"/") {
+			return src
+		}
+	}
+	return o.SrcPath
+}
+
+// tally updates the summary of the source a file was found in.
+func (o *Organizer) tally(filePath string, update func(*SourceSummary)) {
+	source := o.sourceOf(filePath)
+	o.statsMu.Lock()
+	defer o.statsMu.Unlock()
+	if o.sourceStats == nil {
+		o.sourceStats = make(map[string]*SourceSummary)
+	}
+	summary, ok := o.sourceStats[source]
+	if !ok {
+		summary = &SourceSummary{Source: source}
+		o.sourceStats[source] = summary
+	}
+	update(summary)
+}
+
+// SourceSummaries returns what happened to the files of each source folder in the current run.
+func (o *Organizer) SourceSummaries() []SourceSummary {
+	var summaries []SourceSummary
+	for _, src := range append([]string{o.SrcPath}, o.Sources...) {
+		if summary, ok := o.sourceStats[src]; ok {
+			summaries = append(summaries, *summary)
+		}
+	}
+	return summaries
+}
+
+// printSourceSummaries breaks the run down by source folder when there was more than one.
+func (o *Organizer) printSourceSummaries() {
+	summaries := o.SourceSummaries()
+	if len(summaries) < 2 {
+		return
+	}
+	for _, s := range summaries {
+		fmt.Printf("Source %s: %d files, %d grouped, %d skipped, %d duplicates, %d failed\n",
+			s.Source, s.Files, s.Grouped, s.Skipped, s.Duplicates, s.Failed)
+	}
+}
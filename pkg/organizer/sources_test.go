@@ -0,0 +1,60 @@
+package organizer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMultipleSources(t *testing.T) {
+	laptop, phone, library := t.TempDir(), t.TempDir(), t.TempDir()
+	// The same name for different photos, and the same photo in both backups.
+	if err := os.WriteFile(filepath.Join(laptop, "IMG_20230501_143000.xyz"), []byte("laptop"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(phone, "IMG_20230501_143000.xyz"), []byte("phone"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{laptop, phone} {
+		if err := os.WriteFile(filepath.Join(dir, "IMG_20230502_100000.xyz"), []byte("shared"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	org := NewOrganizer(laptop, "ymd", "generated", "seq", "0", "copy")
+	org.Extractors = testChainRegistry()
+	org.Sources = []string{phone, filepath.Join(phone, "nested")}
+	org.OutputRoot = library
+	org.DedupMode = DedupSkip
+	if got := org.sources(); len(got) != 2 {
+		t.Fatalf("Expected the nested source to be left out, got %v", got)
+	}
+	for _, src := range org.sources() {
+		org.AddFileEntries(src)
+	}
+	org.OrganizeFiles(0)
+
+	for name, want := range map[string]string{
+		"20230501/IMG_20230501_143000.xyz":   "laptop",
+		"20230501/IMG_20230501_143000_1.xyz": "phone",
+		"20230502/IMG_20230502_100000.xyz":   "shared",
+	} {
+		if got, err := os.ReadFile(filepath.Join(library, filepath.FromSlash(name))); string(got) != want {
+			t.Errorf("Expected %s to hold %q, got %q (%v)", name, want, got, err)
+		}
+	}
+
+	summaries := org.SourceSummaries()
+	want := []SourceSummary{
+		{Source: laptop, Files: 2, Grouped: 2},
+		{Source: phone, Files: 2, Grouped: 1, Duplicates: 1},
+	}
+	if len(summaries) != len(want) {
+		t.Fatalf("Unexpected summaries %+v", summaries)
+	}
+	for i := range want {
+		if summaries[i] != want[i] {
+			t.Errorf("Summary %d is %+v, want %+v", i, summaries[i], want[i])
+		}
+	}
+}
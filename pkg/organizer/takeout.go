@@ -0,0 +1,231 @@
+package organizer
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tidwall/gjson"
+)
+
+// --- Google Takeout JSON sidecars ---
+
+// DateStepTakeout reads photoTakenTime from a Google Takeout JSON sidecar.
+const DateStepTakeout = "takeout"
+
+// Date sources read from Google Takeout sidecars.
+const (
+	DateSourceTakeoutPhotoTaken DateSource = "takeout:photoTakenTime"
+	DateSourceTakeoutCreation   DateSource = "takeout:creationTime"
+)
+
+// Sidecar handling modes of Organizer.SidecarMode.
+const (
+	SidecarMove = "move" // sidecars are moved or copied next to their media
+	SidecarDrop = "drop" // sidecars are left behind, or deleted when their media is moved
+)
+
+// ParseSidecarMode checks a sidecar mode; "" is SidecarMove.
+func ParseSidecarMode(s string) (string, error) {
+	switch s {
+	case "":
+		return SidecarMove, nil
+	case SidecarMove, SidecarDrop:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown sidecar mode %q (move/drop)", s)
+}
+
+// maxTakeoutName is the length Takeout truncates sidecar names to, not counting ".json".
+const maxTakeoutName = 46
+
+// takeoutSupplemental is the infix newer Takeout exports put before ".json".
+const takeoutSupplemental = ".supplemental-metadata"
+
+// takeoutDuplicatePattern splits a name such as "IMG_0001(1).jpg" into stem, counter and extension.
+var takeoutDuplicatePattern = regexp.MustCompile(`^(.*)(\(\d+\))(\.[^.]*)$`)
+
+// takeoutEditedSuffixes are appended by Google Photos to edited copies, which share the original's sidecar.
+var takeoutEditedSuffixes = []string{"-edited", "-bearbeitet", "-modifié", "-editado", "-modificato", "-bewerkt", "-redigeret"}
+
+// takeoutSidecarNames lists the sidecar names Takeout may have used for a media file, most specific first.
+// Takeout truncates long names and moves the "(n)" duplicate counter behind the extension
+// ("name.jpg(1).json"). Edited copies have no sidecar of their own; the names of the
+// original's sidecar are returned separately.
+func takeoutSidecarNames(base string) (own, original []string) {
+	stem, counter, ext := strings.TrimSuffix(base, filepath.Ext(base)), "", filepath.Ext(base)
+	if m := takeoutDuplicatePattern.FindStringSubmatch(base); m != nil {
+		stem, counter, ext = m[1], m[2], m[3]
+	}
+
+	own = takeoutNames(stem, counter, ext)
+	for _, suffix := range takeoutEditedSuffixes {
+		if trimmed := strings.TrimSuffix(stem, suffix); trimmed != stem {
+			original = append(original, takeoutNames(trimmed, counter, ext)...)
+		}
+	}
+	return own, original
+}
+
+// takeoutNames lists the sidecar names of one media name split into stem, duplicate counter and extension.
+func takeoutNames(stem, counter, ext string) []string {
+	var names []string
+	for _, name := range []string{stem + ext, stem + ext + takeoutSupplemental, stem} {
+		names = append(names, truncateTakeoutName(name)+counter+".json")
+	}
+	if counter != "" {
+		// Some exports keep the counter in front of the extension.
+		names = append(names, truncateTakeoutName(stem+counter+ext)+".json")
+	}
+	return names
+}
+
+// truncateTakeoutName cuts a sidecar name to the length Takeout allows, keeping whole runes.
+func truncateTakeoutName(name string) string {
+	if len(name) <= maxTakeoutName {
+		return name
+	}
+	cut := maxTakeoutName
+	for cut > 0 && !utf8.RuneStart(name[cut]) {
+		cut--
+	}
+	return name[:cut]
+}
+
+// findTakeoutSidecar returns the path of the Takeout sidecar of a media file, or "".
+// owned is false when the sidecar belongs to the original of an edited copy, which
+// must stay with the original when files are grouped.
+func findTakeoutSidecar(filePath string) (sidecar string, owned bool) {
+	dir := filepath.Dir(filePath)
+	own, original := takeoutSidecarNames(filepath.Base(filePath))
+	for i, name := range append(own, original...) {
+		sidecar := filepath.Join(dir, name)
+		if stat, err := os.Stat(sidecar); err == nil && stat.Mode().IsRegular() {
+			return sidecar, i < len(own)
+		}
+	}
+	return "", false
+}
+
+// readTakeoutSidecar reads the capture dates and location of a Takeout sidecar.
+func readTakeoutSidecar(sidecar string) ([]DateCandidate, *GPSInfo, error) {
+	data, err := os.ReadFile(sidecar)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !gjson.ValidBytes(data) {
+		return nil, nil, fmt.Errorf("invalid JSON in %s", sidecar)
+	}
+
+	var candidates []DateCandidate
+	for _, field := range []struct {
+		path   string
+		source DateSource
+	}{
+		{"photoTakenTime.timestamp", DateSourceTakeoutPhotoTaken},
+		{"creationTime.timestamp", DateSourceTakeoutCreation},
+	} {
+		value := gjson.GetBytes(data, field.path)
+		seconds, err := strconv.ParseInt(value.String(), 10, 64)
+		if !value.Exists() || err != nil || seconds <= 0 {
+			continue
+		}
+		// Takeout stores UTC instants, so show them in local time like other UTC sources.
+		candidates = append(candidates, DateCandidate{Time: time.Unix(seconds, 0).In(time.Local), Instant: true, Source: field.source})
+	}
+
+	var gps *GPSInfo
+	for _, path := range []string{"geoDataExif", "geoData"} {
+		geo := gjson.GetBytes(data, path)
+		lat, lon := geo.Get("latitude").Float(), geo.Get("longitude").Float()
+		if lat != 0 || lon != 0 {
+			gps = &GPSInfo{Latitude: lat, Longitude: lon, Altitude: geo.Get("altitude").Float()}
+			break
+		}
+	}
+	return candidates, gps, nil
+}
+
+// takeoutDate returns the photo taken time from the Takeout sidecar of a file.
+func takeoutDate(filePath string, info *MediaInfo) (DateCandidate, bool) {
+	sidecar, _ := findTakeoutSidecar(filePath)
+	if sidecar == "" {
+		return DateCandidate{}, false
+	}
+	candidates, gps, err := readTakeoutSidecar(sidecar)
+	if err != nil || len(candidates) == 0 {
+		return DateCandidate{}, false
+	}
+	if info.GPS == nil {
+		info.GPS = gps
+	}
+	return candidates[0], true
+}
+
+// groupSidecars moves, copies or drops the sidecars of an entry whose media has been grouped.
+func (o *Organizer) groupSidecars(fileEntry FileData) {
+	for _, sidecar := range fileEntry.Sidecars {
+		// A "name.xmp" sidecar is shared by RAW+JPEG pairs and goes with the first of them.
+		if !o.claimSidecar(sidecar) {
+			continue
+		}
+		if _, err := os.Stat(sidecar); err != nil {
+			continue
+		}
+
+		if o.SidecarMode == SidecarDrop {
+			if o.GroupMode == "move" {
+				if err := o.perform(PlanAction{Action: ActionRemove, Source: sidecar, Decision: "sidecar dropped"}); err != nil {
+					log.Printf("Error removing sidecar: %v", err)
+				}
+			}
+			continue
+		}
+
+		newPath := sidecarPath(sidecar, fileEntry.Path, fileEntry.NewPath)
+		if o.VerboseMode == "1" {
+			fmt.Printf("%s processing sidecar: %s -> %s\n", o.GroupMode, sidecar, newPath)
+		}
+		err := o.perform(PlanAction{Action: o.GroupMode, Source: sidecar, Destination: newPath, Decision: "sidecar of " + fileEntry.Path})
+		if err != nil {
+			switch o.GroupMode {
+			case "copy":
+				log.Printf("Error copying sidecar: %v", err)
+			case "move":
+				log.Printf("Error moving sidecar: %v", err)
+			}
+		}
+	}
+}
+
+// claimSidecar reports whether a sidecar is still to be grouped and marks it as grouped. A dry
+// run plans it only once, too, although it does not move.
+func (o *Organizer) claimSidecar(sidecar string) bool {
+	o.planMu.Lock()
+	defer o.planMu.Unlock()
+	if o.sidecars[sidecar] {
+		return false
+	}
+	if o.sidecars == nil {
+		o.sidecars = make(map[string]bool)
+	}
+	o.sidecars[sidecar] = true
+	return true
+}
+
+// sidecarPath returns the new path of a sidecar whose media moves from mediaPath to newPath.
+// The sidecar is renamed after its media, so "IMG_0001.jpg(1).json" becomes "IMG_0001(1).jpg.json";
+// a "name.xmp" sidecar keeps that form so Lightroom still finds it.
+func sidecarPath(sidecar, mediaPath, newPath string) string {
+	ext := filepath.Ext(sidecar)
+	if filepath.Base(sidecar) == strings.TrimSuffix(filepath.Base(mediaPath), filepath.Ext(mediaPath))+ext {
+		return strings.TrimSuffix(newPath, filepath.Ext(newPath)) + ext
+	}
+	return newPath + ext
+}
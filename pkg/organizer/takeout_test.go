@@ -0,0 +1,113 @@
+package organizer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testTakeoutJSON is a Takeout sidecar taken at 2023-05-01 14:30:00 UTC.
+const testTakeoutJSON = `{
+  "title": "IMG_0001.jpg",
+  "creationTime": {"timestamp": "1700000000", "formatted": "Nov 14, 2023, 10:13:20 PM UTC"},
+  "photoTakenTime": {"timestamp": "1682951400", "formatted": "May 1, 2023, 2:30:00 PM UTC"},
+  "geoData": {"latitude": 48.8584, "longitude": 2.2945, "altitude": 35.0}
+}`
+
+// writeTestFiles creates empty or JSON files with the given names in dir.
+func writeTestFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		content := []byte("custom")
+		if filepath.Ext(name) == ".json" {
+			content = []byte(testTakeoutJSON)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+}
+
+func TestFindTakeoutSidecar(t *testing.T) {
+	tests := []struct {
+		media   string
+		sidecar string
+		owned   bool
+	}{
+		{"IMG_0001.xyz", "IMG_0001.xyz.json", true},
+		{"IMG_0002(1).xyz", "IMG_0002.xyz(1).json", true},
+		{"IMG_0003.xyz", "IMG_0003.xyz.supplemental-metadata.json", true},
+		{"IMG_0004.xyz", "IMG_0004.json", true},
+		{"IMG_0005-edited.xyz", "IMG_0005.xyz.json", false},
+		{"Screenshot_2023-05-01-14-30-00-123_com.android.chrome.xyz", "Screenshot_2023-05-01-14-30-00-123_com.android.json", true},
+		{"Screenshot_20230501-143000_Chrome.xyz", "Screenshot_20230501-143000_Chrome.xyz.suppleme.json", true},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		writeTestFiles(t, dir, tt.media, tt.sidecar)
+		sidecar, owned := findTakeoutSidecar(filepath.Join(dir, tt.media))
+		if filepath.Base(sidecar) != tt.sidecar || owned != tt.owned {
+			t.Errorf("findTakeoutSidecar(%q) = %q, %v; want %q, %v", tt.media, filepath.Base(sidecar), owned, tt.sidecar, tt.owned)
+		}
+	}
+
+	dir := t.TempDir()
+	writeTestFiles(t, dir, "IMG_0001.xyz", "IMG_0001(1).xyz.json")
+	if sidecar, _ := findTakeoutSidecar(filepath.Join(dir, "IMG_0001.xyz")); sidecar != "" {
+		t.Errorf("Expected no sidecar, got %q", sidecar)
+	}
+}
+
+func TestResolveDateTakeout(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, "IMG_0001.xyz", "IMG_0001.xyz.json")
+
+	org := NewOrganizer(dir, "ymd", "generated", "seq", "0", "copy")
+	org.Extractors = testChainRegistry()
+	info, err := org.resolveDate(filepath.Join(dir, "IMG_0001.xyz"))
+	if err != nil {
+		t.Fatalf("resolveDate returned error: %v", err)
+	}
+	if want := time.Unix(1682951400, 0); info.DateSource != DateSourceTakeoutPhotoTaken || !info.CaptureTime.Equal(want) {
+		t.Errorf("Expected %s from the sidecar, got %s from %s", want, info.CaptureTime, info.DateSource)
+	}
+	if info.GPS == nil || info.GPS.Latitude != 48.8584 || info.GPS.Longitude != 2.2945 {
+		t.Errorf("Expected GPS from the sidecar, got %+v", info.GPS)
+	}
+	if len(info.Sidecars) != 1 || filepath.Base(info.Sidecars[0]) != "IMG_0001.xyz.json" {
+		t.Errorf("Expected the sidecar to be recorded, got %v", info.Sidecars)
+	}
+}
+
+func TestGroupTakeoutSidecars(t *testing.T) {
+	for _, mode := range []string{SidecarMove, SidecarDrop} {
+		dir := t.TempDir()
+		writeTestFiles(t, dir, "IMG_0001-edited.xyz", "IMG_0001.xyz", "IMG_0001.xyz.json", "IMG_0002(1).xyz", "IMG_0002.xyz(1).json")
+
+		org := NewOrganizer(dir, "ymd", "generated", "seq", "0", "move")
+		org.Extractors = testChainRegistry()
+		org.SidecarMode = mode
+		org.AddFileEntries(dir)
+		org.OrganizeFiles(0)
+
+		day := time.Unix(1682951400, 0).Format("20060102")
+		for _, name := range []string{"IMG_0001-edited.xyz", "IMG_0001.xyz", "IMG_0002(1).xyz"} {
+			if _, err := os.Stat(filepath.Join(dir, "generated", day, name)); err != nil {
+				t.Errorf("%s: expected %s to be moved: %v", mode, name, err)
+			}
+		}
+		for _, name := range []string{"IMG_0001.xyz.json", "IMG_0002(1).xyz.json"} {
+			_, err := os.Stat(filepath.Join(dir, "generated", day, name))
+			if (err == nil) != (mode == SidecarMove) {
+				t.Errorf("%s: unexpected state of sidecar %s: %v", mode, name, err)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(dir, "generated", day, "IMG_0001-edited.xyz.json")); err == nil {
+			t.Errorf("%s: edited copy took the original's sidecar", mode)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "IMG_0001.xyz.json")); err == nil {
+			t.Errorf("%s: sidecar left in the source folder", mode)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+package organizer
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Folder and file name templates ---
+
+// Template is a parsed layout such as "{year}/{month:02}-{monthname}/{date:2006-01-02}".
+// A variable is written {name} or {name:format}; "{{" and "}}" stand for literal braces.
+type Template struct {
+	source   string
+	segments []templateSegment
+}
+
+// templateSegment is a literal piece of a template or a variable with its format.
+type templateSegment struct {
+	literal  string
+	variable string
+	format   string
+}
+
+// templateVarKind tells how a variable's format is interpreted.
+type templateVarKind int
+
+const (
+	templateNumber templateVarKind = iota // format "02" pads with zeros to two digits
+	templateText                          // format "lower" or "upper" changes the case
+	templateName                          // month and weekday names in the run's language; format "short" abbreviates
+	templateLayout                        // format is a Go time layout
+)
+
+// templateVars lists the variables a template may use.
+var templateVars = map[string]templateVarKind{
+	"year":      templateNumber,
+	"month":     templateNumber,
+	"day":       templateNumber,
+	"hour":      templateNumber,
+	"minute":    templateNumber,
+	"second":    templateNumber,
+	"week":      templateNumber, // ISO 8601 week number
+	"weekyear":  templateNumber, // year the ISO week belongs to
+	"quarter":   templateNumber,
+	"monthname": templateName,
+	"weekday":   templateName,
+	"date":      templateLayout,
+	"make":      templateText,
+	"model":     templateText,
+	"camera":    templateText, // model, prefixed by the make unless it already starts with it
+	"lens":      templateText,
+	"type":      templateText,   // image, raw or video
+	"ext":       templateText,   // extension without the dot; with the dot in rename templates
+	"name":      templateText,   // original file name without the extension
+	"seq":       templateNumber, // per-day sequence number within the run, rename templates only
+	"parent":    templateText,   // name of the folder the file was found in
+}
+
+// templateUnknown replaces empty text variables so no path component is left empty.
+const templateUnknown = "Unknown"
+
+// Folder layouts of the legacy FolderFormat values.
+const (
+	FolderFormatYMD = "{date:20060102}"
+	FolderFormatYM  = "{date:200601}"
+)
+
+// ParseTemplate parses a template and checks its variables and formats.
+func ParseTemplate(s string) (*Template, error) {
+	t := &Template{source: s}
+	var literal strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '{' && strings.HasPrefix(s[i:], "{{"), c == '}' && strings.HasPrefix(s[i:], "}}"):
+			literal.WriteByte(c)
+			i++
+		case c == '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed variable in template %q", s)
+			}
+			segment, err := parseTemplateVar(s[i+1 : i+end])
+			if err != nil {
+				return nil, fmt.Errorf("template %q: %w", s, err)
+			}
+			if literal.Len() > 0 {
+				t.segments = append(t.segments, templateSegment{literal: literal.String()})
+				literal.Reset()
+			}
+			t.segments = append(t.segments, segment)
+			i += end
+		case c == '}':
+			return nil, fmt.Errorf("unexpected } in template %q", s)
+		default:
+			literal.WriteByte(c)
+		}
+	}
+	if literal.Len() > 0 {
+		t.segments = append(t.segments, templateSegment{literal: literal.String()})
+	}
+	return t, nil
+}
+
+// parseTemplateVar parses the inside of a {name:format} variable.
+func parseTemplateVar(s string) (templateSegment, error) {
+	name, format, _ := strings.Cut(s, ":")
+	kind, ok := templateVars[name]
+	if !ok {
+		return templateSegment{}, fmt.Errorf("unknown variable {%s}", name)
+	}
+
+	valid := format == ""
+	switch kind {
+	case templateNumber:
+		_, err := strconv.Atoi(format)
+		valid = valid || (strings.HasPrefix(format, "0") && err == nil)
+	case templateText:
+		valid = valid || format == "lower" || format == "upper"
+	case templateName:
+		valid = valid || format == "short" || format == "lower" || format == "upper"
+	case templateLayout:
+		valid = true
+	}
+	if !valid {
+		return templateSegment{}, fmt.Errorf("invalid format %q for {%s}", format, name)
+	}
+	return templateSegment{variable: name, format: format}, nil
+}
+
+// uses reports whether the template contains the variable.
+func (t *Template) uses(variable string) bool {
+	for _, segment := range t.segments {
+		if segment.variable == variable {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the template as written.
+func (t *Template) String() string {
+	return t.source
+}
+
+// templateData holds what a template is rendered from.
+type templateData struct {
+	time     time.Time
+	info     *MediaInfo
+	filePath string
+	locale   *locale // names for {monthname} and {weekday}; English when nil
+	seq      int     // value of {seq}
+	fileName bool    // rendering a file name rather than a folder path
+}
+
+// render fills in the template. Values taken from metadata are made safe for use as a
+// single path component; "/" in the template itself separates nested folders.
+func (t *Template) render(data templateData) string {
+	var b strings.Builder
+	for _, segment := range t.segments {
+		if segment.variable == "" {
+			b.WriteString(segment.literal)
+			continue
+		}
+		b.WriteString(segment.value(data))
+	}
+	return b.String()
+}
+
+// value renders one variable.
+func (s templateSegment) value(data templateData) string {
+	tm := data.time
+	names := data.locale
+	if names == nil {
+		names = locales[DefaultLanguage]
+	}
+	switch s.variable {
+	case "date":
+		layout := s.format
+		if layout == "" {
+			layout = "20060102"
+		}
+		return tm.Format(layout)
+	case "monthname":
+		return applyTextFormat(names.monthName(tm.Month(), s.format == "short"), s.format)
+	case "weekday":
+		return applyTextFormat(names.weekdayName(tm.Weekday(), s.format == "short"), s.format)
+	case "ext":
+		if data.fileName {
+			// A file without an extension keeps having none.
+			ext := sanitizePathComponent(strings.TrimPrefix(filepath.Ext(data.filePath), "."))
+			if ext == "" {
+				return ""
+			}
+			return "." + applyTextFormat(ext, s.format)
+		}
+	}
+
+	if kind := templateVars[s.variable]; kind == templateNumber {
+		var n int
+		switch s.variable {
+		case "year":
+			n = tm.Year()
+		case "month":
+			n = int(tm.Month())
+		case "day":
+			n = tm.Day()
+		case "hour":
+			n = tm.Hour()
+		case "minute":
+			n = tm.Minute()
+		case "second":
+			n = tm.Second()
+		case "week":
+			_, n = tm.ISOWeek()
+		case "weekyear":
+			n, _ = tm.ISOWeek()
+		case "quarter":
+			n = (int(tm.Month())-1)/3 + 1
+		case "seq":
+			n = data.seq
+		}
+		if s.format != "" {
+			width, _ := strconv.Atoi(s.format)
+			return fmt.Sprintf("%0*d", width, n)
+		}
+		return strconv.Itoa(n)
+	}
+
+	info := data.info
+	if info == nil {
+		info = &MediaInfo{}
+	}
+	var value string
+	switch s.variable {
+	case "make":
+		value = info.Make
+	case "model":
+		value = info.Model
+	case "camera":
+		value = cameraName(info.Make, info.Model)
+	case "lens":
+		value = info.Lens
+	case "type":
+		value = mediaType(info.ContentType)
+	case "ext":
+		value = strings.TrimPrefix(filepath.Ext(data.filePath), ".")
+	case "parent":
+		value = filepath.Base(filepath.Dir(data.filePath))
+	case "name":
+		base := filepath.Base(data.filePath)
+		value = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	value = sanitizePathComponent(value)
+	if value == "" {
+		value = templateUnknown
+	}
+	return applyTextFormat(value, s.format)
+}
+
+// applyTextFormat applies the "lower" and "upper" formats.
+func applyTextFormat(value, format string) string {
+	switch format {
+	case "lower":
+		return strings.ToLower(value)
+	case "upper":
+		return strings.ToUpper(value)
+	}
+	return value
+}
+
+// cameraName joins make and model, leaving out the make when the model already starts with it
+// ("Canon" + "Canon EOS R5" is "Canon EOS R5", "SONY" + "ILCE-7M3" is "SONY ILCE-7M3").
+func cameraName(cameraMake, model string) string {
+	cameraMake, model = strings.TrimSpace(cameraMake), strings.TrimSpace(model)
+	switch {
+	case model == "":
+		return cameraMake
+	case cameraMake == "", strings.HasPrefix(strings.ToLower(model), strings.ToLower(strings.Fields(cameraMake)[0])):
+		return model
+	}
+	return cameraMake + " " + model
+}
+
+// mediaType returns "video", "raw" or "image" for a content type.
+func mediaType(contentType string) string {
+	switch {
+	case strings.HasPrefix(contentType, "video/"):
+		return "video"
+	case strings.HasPrefix(contentType, "image/x-"):
+		// All registered image/x-* types are camera RAW formats.
+		return "raw"
+	case strings.HasPrefix(contentType, "image/"):
+		return "image"
+	}
+	return ""
+}
+
+// sanitizePathComponent makes a metadata value safe as a single file or folder name.
+func sanitizePathComponent(value string) string {
+	value = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		if r < 0x20 {
+			return -1
+		}
+		return r
+	}, value)
+	value = strings.Trim(strings.TrimSpace(value), ".")
+	return value
+}
+
+// folderTemplate returns the parsed FolderFormat. "ymd" and "ym" stand for their legacy
+// layouts; an invalid template falls back to "ymd".
+func (o *Organizer) folderTemplate() *Template {
+	if o.folderTmpl != nil && o.folderTmpl.source == o.FolderFormat {
+		return o.folderTmpl
+	}
+	tmpl, err := ParseFolderFormat(o.FolderFormat)
+	if err != nil {
+		log.Printf("Invalid folder format, using ymd: %v", err)
+		tmpl, _ = ParseTemplate(FolderFormatYMD)
+	}
+	tmpl.source = o.FolderFormat
+	o.folderTmpl = tmpl
+	return tmpl
+}
+
+// ParseFolderFormat parses a folder format: "ymd", "ym" or a template.
+func ParseFolderFormat(format string) (*Template, error) {
+	switch format {
+	case "", "ymd":
+		return ParseTemplate(FolderFormatYMD)
+	case "ym":
+		return ParseTemplate(FolderFormatYM)
+	}
+	tmpl, err := ParseTemplate(format)
+	if err == nil && tmpl.uses("seq") {
+		return nil, fmt.Errorf("template %q: {seq} can only be used to rename files", format)
+	}
+	return tmpl, err
+}
+
+// folderKey returns the folder, relative to the generated folder, a file is grouped into.
+func (o *Organizer) folderKey(filePath string, info *MediaInfo) string {
+	key := o.folderTemplate().render(templateData{time: o.bucketTime(info), info: info, filePath: filePath, locale: o.locale()})
+
+	// Keep the result inside the generated folder whatever the template produced.
+	var parts []string
+	for _, part := range strings.Split(key, "/") {
+		part = strings.TrimSpace(part)
+		if part == "" || part == "." || part == ".." {
+			continue
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return templateUnknown
+	}
+	return strings.Join(parts, "/")
+}
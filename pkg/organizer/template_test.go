@@ -0,0 +1,123 @@
+package organizer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTemplateRender(t *testing.T) {
+	info := &MediaInfo{
+		CaptureTime: time.Date(2023, 1, 1, 9, 5, 3, 0, time.UTC),
+		Make:        "Canon",
+		Model:       "Canon EOS R5",
+		Lens:        "RF24-105mm F4 L IS USM",
+		ContentType: "image/x-canon-cr3",
+	}
+	filePath := filepath.Join("/photos", "Trip: Rome", "IMG_0001.CR3")
+
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"{year}/{month:02}-{monthname}/{date:2006-01-02}", "2023/01-January/2023-01-01"},
+		{"{weekyear}-W{week:02}/{weekday:short}", "2022-W52/Sun"},
+		{"{year}/Q{quarter}/{hour:02}{minute:02}{second:02}", "2023/Q1/090503"},
+		{"{camera}/{lens}", "Canon EOS R5/RF24-105mm F4 L IS USM"},
+		{"{make:upper}/{model:lower}/{type}/{ext:lower}", "CANON/canon eos r5/raw/cr3"},
+		{"{parent}/{{{monthname:lower}}}", "Trip_ Rome/{january}"},
+		{"{date}", "20230101"},
+	}
+	for _, tt := range tests {
+		tmpl, err := ParseTemplate(tt.template)
+		if err != nil {
+			t.Fatalf("ParseTemplate(%q) returned error: %v", tt.template, err)
+		}
+		if got := tmpl.render(templateData{time: info.CaptureTime, info: info, filePath: filePath}); got != tt.want {
+			t.Errorf("Template %q rendered %q, want %q", tt.template, got, tt.want)
+		}
+	}
+
+	for _, s := range []string{"{year", "{film}", "{month:2}", "{make:title}", "year}"} {
+		if _, err := ParseTemplate(s); err == nil {
+			t.Errorf("Expected error for template %q", s)
+		}
+	}
+}
+
+func TestLocalizedNames(t *testing.T) {
+	tests := []struct {
+		language string
+		template string
+		want     string
+	}{
+		{"de", "{year}/{month:02} - {monthname}", "2023/05 - Mai"},
+		{"de_DE.UTF-8", "{monthname:short}/{weekday}", "Mai/Montag"},
+		{"fr", "{monthname:upper} {weekday:short}", "MAI lun"},
+		{"pl", "{monthname}/{weekday}", "maj/poniedziałek"},
+		{"ja", "{year}年{monthname}", "2023年5月"},
+		{"no", "{weekday:short}", "man"},
+		{"", "{monthname}/{weekday:short}", "May/Mon"},
+	}
+	info := &MediaInfo{CaptureTime: time.Date(2023, 5, 1, 14, 30, 0, 0, time.UTC)}
+	org := NewOrganizer("", "ymd", "generated", "seq", "0", "copy")
+	for _, tt := range tests {
+		org.FolderFormat, org.Language = tt.template, tt.language
+		if got := org.folderKey("photo.jpg", info); got != tt.want {
+			t.Errorf("Language %q, template %q gave %q, want %q", tt.language, tt.template, got, tt.want)
+		}
+	}
+
+	if _, err := ParseLanguage("tlh"); err == nil {
+		t.Error("Expected error for unsupported language")
+	}
+	for code, names := range locales {
+		for _, name := range append(append(names.months[:], names.shortMonths[:]...), append(names.weekdays[:], names.shortWeekdays[:]...)...) {
+			if name == "" || sanitizePathComponent(name) != name {
+				t.Errorf("Language %s has name %q that is not a valid folder name", code, name)
+			}
+		}
+	}
+}
+
+func TestFolderKey(t *testing.T) {
+	info := &MediaInfo{CaptureTime: time.Date(2023, 5, 1, 14, 30, 0, 0, time.UTC), Model: "../../etc", ContentType: "video/mp4"}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"ymd", "20230501"},
+		{"ym", "202305"},
+		{"", "20230501"},
+		{"{type}/{year}//{model}", "video/2023/_.._etc"},
+		{"{make}/{year}", "Unknown/2023"},
+		{"{year", "20230501"},
+	}
+	org := NewOrganizer("", "ymd", "generated", "seq", "0", "copy")
+	for _, tt := range tests {
+		org.FolderFormat = tt.format
+		if got := org.folderKey("clip.mp4", info); got != tt.want {
+			t.Errorf("Folder format %q gave %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestOrganizeNestedFolders(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, "IMG_20230501_143000.xyz", "IMG_20231224_100000.xyz")
+
+	org := NewOrganizer(dir, "{year}/{month:02}-{monthname}/{date:2006-01-02}", "generated", "seq", "0", "copy")
+	org.Extractors = testChainRegistry()
+	org.AddFileEntries(dir)
+	org.OrganizeFiles(0)
+
+	for _, name := range []string{
+		"2023/05-May/2023-05-01/IMG_20230501_143000.xyz",
+		"2023/12-December/2023-12-24/IMG_20231224_100000.xyz",
+	} {
+		if _, err := os.Stat(filepath.Join(dir, "generated", filepath.FromSlash(name))); err != nil {
+			t.Errorf("Expected %s: %v", name, err)
+		}
+	}
+}
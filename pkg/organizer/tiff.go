@@ -0,0 +1,349 @@
+package organizer
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// --- Native TIFF IFD reader used for RAW and TIFF files ---
+
+// errNotTIFF is returned when data does not start with a TIFF header.
+var errNotTIFF = errors.New("not a TIFF structure")
+
+// TIFF tags read by the native reader.
+const (
+	tiffTagImageWidth          = 0x0100
+	tiffTagImageLength         = 0x0101
+	tiffTagMake                = 0x010F
+	tiffTagModel               = 0x0110
+	tiffTagDateTime            = 0x0132
+	tiffTagSubIFDs             = 0x014A
+	tiffTagExifIFD             = 0x8769
+	tiffTagGPSIFD              = 0x8825
+	tiffTagDateTimeOriginal    = 0x9003
+	tiffTagDateTimeDigitized   = 0x9004
+	tiffTagOffsetTime          = 0x9010
+	tiffTagOffsetTimeOriginal  = 0x9011
+	tiffTagOffsetTimeDigitized = 0x9012
+	tiffTagSubSecTime          = 0x9290
+	tiffTagSubSecTimeOriginal  = 0x9291
+	tiffTagSubSecTimeDigitized = 0x9292
+	tiffTagBodySerialNumber    = 0xA431
+	tiffTagLensModel           = 0xA434
+	tiffTagCameraSerialNumber  = 0xC62F
+	tiffTagPixelXDimension     = 0xA002
+	tiffTagPixelYDimension     = 0xA003
+
+	gpsTagLatitudeRef  = 0x0001
+	gpsTagLatitude     = 0x0002
+	gpsTagLongitudeRef = 0x0003
+	gpsTagLongitude    = 0x0004
+	gpsTagAltitude     = 0x0006
+	gpsTagTimeStamp    = 0x0007
+	gpsTagDateStamp    = 0x001D
+)
+
+// maxIFDEntries bounds the entries read from a single IFD of a damaged file.
+const maxIFDEntries = 1024
+
+// tiffTypeSizes maps TIFF field types to their size in bytes.
+var tiffTypeSizes = map[uint16]uint32{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8, 13: 4,
+}
+
+// tiffEntry is one directory entry of an IFD.
+type tiffEntry struct {
+	Type  uint16
+	Count uint32
+	Value [4]byte // the value itself when it fits, otherwise its offset
+}
+
+// tiffReader reads IFDs of a TIFF structure starting at base within r.
+type tiffReader struct {
+	r     io.ReadSeeker
+	base  int64
+	order binary.ByteOrder
+}
+
+// newTIFFReader parses the TIFF header at base and returns a reader with the offset of IFD0.
+// The ORF ("IIRO"/"IIRS") and RW2 ("IIU") variants of the header are accepted too.
+func newTIFFReader(r io.ReadSeeker, base int64) (*tiffReader, uint32, error) {
+	header := make([]byte, 8)
+	if _, err := r.Seek(base, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, 0, errNotTIFF
+	}
+
+	t := &tiffReader{r: r, base: base}
+	switch string(header[0:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return nil, 0, errNotTIFF
+	}
+
+	switch t.order.Uint16(header[2:4]) {
+	case 42, 0x4F52, 0x5352, 0x55:
+	default:
+		return nil, 0, errNotTIFF
+	}
+	return t, t.order.Uint32(header[4:8]), nil
+}
+
+// extractTIFF reads capture metadata from the IFD0 and Exif IFD of a TIFF image or TIFF-based RAW file.
+func extractTIFF(r io.ReadSeeker) (*MediaInfo, error) {
+	info, err := mediaInfoFromTIFF(r, 0)
+	if errors.Is(err, errNotTIFF) {
+		return nil, ErrNoCaptureDate
+	}
+	return info, err
+}
+
+// tiffIFD is a parsed image file directory together with the reader that resolves its values.
+// The zero value is an empty directory.
+type tiffIFD struct {
+	t       *tiffReader
+	entries map[uint16]tiffEntry
+}
+
+// readIFD reads the IFD at offset and returns it with the offset of the next IFD.
+func (t *tiffReader) readIFD(offset uint32) (tiffIFD, uint32, error) {
+	if offset == 0 {
+		return tiffIFD{}, 0, errNotTIFF
+	}
+	if _, err := t.r.Seek(t.base+int64(offset), io.SeekStart); err != nil {
+		return tiffIFD{}, 0, err
+	}
+
+	countBytes := make([]byte, 2)
+	if _, err := io.ReadFull(t.r, countBytes); err != nil {
+		return tiffIFD{}, 0, err
+	}
+	count := int(t.order.Uint16(countBytes))
+	if count == 0 || count > maxIFDEntries {
+		return tiffIFD{}, 0, fmt.Errorf("invalid IFD entry count %d at offset %d", count, offset)
+	}
+
+	data := make([]byte, count*12+4)
+	if _, err := io.ReadFull(t.r, data); err != nil {
+		return tiffIFD{}, 0, err
+	}
+
+	ifd := tiffIFD{t: t, entries: make(map[uint16]tiffEntry, count)}
+	for i := 0; i < count; i++ {
+		raw := data[i*12 : i*12+12]
+		entry := tiffEntry{
+			Type:  t.order.Uint16(raw[2:4]),
+			Count: t.order.Uint32(raw[4:8]),
+		}
+		copy(entry.Value[:], raw[8:12])
+		ifd.entries[t.order.Uint16(raw[0:2])] = entry
+	}
+	return ifd, t.order.Uint32(data[count*12:]), nil
+}
+
+// readFirstIFD parses the TIFF header at base and reads IFD0.
+func readFirstIFD(r io.ReadSeeker, base int64) (tiffIFD, error) {
+	t, offset, err := newTIFFReader(r, base)
+	if err != nil {
+		return tiffIFD{}, err
+	}
+	ifd, _, err := t.readIFD(offset)
+	return ifd, err
+}
+
+// sub reads the directory referenced by a pointer tag such as the Exif or GPS IFD.
+func (d tiffIFD) sub(tag uint16) tiffIFD {
+	offset := d.uint(tag)
+	if offset == 0 {
+		return tiffIFD{}
+	}
+	ifd, _, err := d.t.readIFD(offset)
+	if err != nil {
+		return tiffIFD{}
+	}
+	return ifd
+}
+
+// valueBytes returns the raw bytes of an entry's value.
+func (d tiffIFD) valueBytes(tag uint16) (tiffEntry, []byte, error) {
+	e, ok := d.entries[tag]
+	if !ok {
+		return e, nil, errNotTIFF
+	}
+	t := d.t
+	size := tiffTypeSizes[e.Type] * e.Count
+	if size == 0 || size > maxBoxRead {
+		return e, nil, fmt.Errorf("invalid TIFF value size %d", size)
+	}
+	if size <= 4 {
+		return e, e.Value[:size], nil
+	}
+	if _, err := t.r.Seek(t.base+int64(t.order.Uint32(e.Value[:])), io.SeekStart); err != nil {
+		return e, nil, err
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(t.r, data); err != nil {
+		return e, nil, err
+	}
+	return e, data, nil
+}
+
+// ascii returns an ASCII entry as a trimmed string.
+func (d tiffIFD) ascii(tag uint16) string {
+	_, data, err := d.valueBytes(tag)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(string(data), "\x00"))
+}
+
+// uints returns the values of a BYTE, SHORT or LONG entry.
+func (d tiffIFD) uints(tag uint16) []uint32 {
+	e, data, err := d.valueBytes(tag)
+	if err != nil {
+		return nil
+	}
+
+	order := d.t.order
+	var values []uint32
+	switch e.Type {
+	case 1, 7:
+		for _, b := range data {
+			values = append(values, uint32(b))
+		}
+	case 3:
+		for i := 0; i+2 <= len(data); i += 2 {
+			values = append(values, uint32(order.Uint16(data[i:])))
+		}
+	case 4, 13:
+		for i := 0; i+4 <= len(data); i += 4 {
+			values = append(values, order.Uint32(data[i:]))
+		}
+	}
+	return values
+}
+
+// uint returns the first value of an integer entry, or 0.
+func (d tiffIFD) uint(tag uint16) uint32 {
+	if values := d.uints(tag); len(values) > 0 {
+		return values[0]
+	}
+	return 0
+}
+
+// rationals returns the values of a RATIONAL entry as floats.
+func (d tiffIFD) rationals(tag uint16) []float64 {
+	e, data, err := d.valueBytes(tag)
+	if err != nil || e.Type != 5 {
+		return nil
+	}
+
+	order := d.t.order
+	var values []float64
+	for i := 0; i+8 <= len(data); i += 8 {
+		denominator := order.Uint32(data[i+4:])
+		if denominator == 0 {
+			return nil
+		}
+		values = append(values, float64(order.Uint32(data[i:]))/float64(denominator))
+	}
+	return values
+}
+
+// mediaInfoFromTIFF reads capture metadata from the IFD0, Exif and GPS directories
+// of the TIFF structure at base.
+func mediaInfoFromTIFF(r io.ReadSeeker, base int64) (*MediaInfo, error) {
+	ifd0, err := readFirstIFD(r, base)
+	if err != nil {
+		return nil, err
+	}
+
+	// RAW files keep the full-size image in a SubIFD and a preview in IFD0.
+	var subIFDs []tiffIFD
+	for _, offset := range ifd0.uints(tiffTagSubIFDs) {
+		if sub, _, err := ifd0.t.readIFD(offset); err == nil {
+			subIFDs = append(subIFDs, sub)
+		}
+	}
+
+	return mediaInfoFromIFDs(ifd0, ifd0.sub(tiffTagExifIFD), ifd0.sub(tiffTagGPSIFD), subIFDs...)
+}
+
+// mediaInfoFromIFDs builds a MediaInfo from already located IFD0, Exif, GPS and image directories.
+func mediaInfoFromIFDs(ifd0, exifIFD, gpsIFD tiffIFD, imageIFDs ...tiffIFD) (*MediaInfo, error) {
+	info := &MediaInfo{
+		Make:  ifd0.ascii(tiffTagMake),
+		Model: ifd0.ascii(tiffTagModel),
+	}
+	info.Lens = exifIFD.ascii(tiffTagLensModel)
+	if info.Serial = exifIFD.ascii(tiffTagBodySerialNumber); info.Serial == "" {
+		info.Serial = ifd0.ascii(tiffTagCameraSerialNumber)
+	}
+
+	for _, ifd := range append([]tiffIFD{ifd0}, imageIFDs...) {
+		if width := int(ifd.uint(tiffTagImageWidth)); width > info.Width {
+			info.Width, info.Height = width, int(ifd.uint(tiffTagImageLength))
+		}
+	}
+	if width := int(exifIFD.uint(tiffTagPixelXDimension)); width > info.Width {
+		info.Width, info.Height = width, int(exifIFD.uint(tiffTagPixelYDimension))
+	}
+
+	var candidates []DateCandidate
+	for _, date := range []struct {
+		ifd                 tiffIFD
+		tag, subSec, offset uint16
+		source              DateSource
+	}{
+		{exifIFD, tiffTagDateTimeOriginal, tiffTagSubSecTimeOriginal, tiffTagOffsetTimeOriginal, DateSourceExifOriginal},
+		{exifIFD, tiffTagDateTimeDigitized, tiffTagSubSecTimeDigitized, tiffTagOffsetTimeDigitized, DateSourceExifDigitized},
+		{ifd0, tiffTagDateTime, tiffTagSubSecTime, tiffTagOffsetTime, DateSourceExifDateTime},
+	} {
+		// The SubSecTime and OffsetTime tags of DateTime live in the Exif IFD.
+		candidate, ok := exifDateCandidate(date.ifd.ascii(date.tag), exifIFD.ascii(date.subSec), exifIFD.ascii(date.offset), date.source)
+		if ok {
+			candidates = append(candidates, candidate)
+		}
+	}
+	if hms := gpsIFD.rationals(gpsTagTimeStamp); len(hms) == 3 {
+		if gpsTime, ok := gpsDateTime(gpsIFD.ascii(gpsTagDateStamp), hms[0]+hms[1]/60+hms[2]/3600); ok {
+			candidates = applyGPSTime(candidates, gpsTime)
+		}
+	}
+
+	info.GPS = gpsIFD.gps()
+
+	return info, info.setDates(candidates)
+}
+
+// gps converts the position tags of a GPS IFD to decimal degrees.
+func (d tiffIFD) gps() *GPSInfo {
+	lat := d.rationals(gpsTagLatitude)
+	lon := d.rationals(gpsTagLongitude)
+	if len(lat) != 3 || len(lon) != 3 {
+		return nil
+	}
+
+	gps := &GPSInfo{
+		Latitude:  lat[0] + lat[1]/60 + lat[2]/3600,
+		Longitude: lon[0] + lon[1]/60 + lon[2]/3600,
+	}
+	if d.ascii(gpsTagLatitudeRef) == "S" {
+		gps.Latitude = -gps.Latitude
+	}
+	if d.ascii(gpsTagLongitudeRef) == "W" {
+		gps.Longitude = -gps.Longitude
+	}
+	if alt := d.rationals(gpsTagAltitude); len(alt) > 0 {
+		gps.Altitude = alt[0]
+	}
+	return gps
+}
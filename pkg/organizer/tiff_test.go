@@ -0,0 +1,222 @@
+package organizer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testTIFFTag is one entry for testTIFF.
+type testTIFFTag struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	data  []byte
+}
+
+// testASCII builds an ASCII TIFF entry.
+func testASCII(tag uint16, value string) testTIFFTag {
+	data := append([]byte(value), 0)
+	return testTIFFTag{tag: tag, typ: 2, count: uint32(len(data)), data: data}
+}
+
+// testRationals builds a RATIONAL TIFF entry from numerator/denominator pairs.
+func testRationals(order binary.ByteOrder, tag uint16, values ...uint32) testTIFFTag {
+	data := make([]byte, 4*len(values))
+	for i, v := range values {
+		order.PutUint32(data[i*4:], v)
+	}
+	return testTIFFTag{tag: tag, typ: 5, count: uint32(len(values) / 2), data: data}
+}
+
+// testTIFF builds a TIFF structure with IFD0 and optional Exif and GPS IFDs.
+// The pointer entries to the Exif and GPS IFDs are added automatically.
+func testTIFF(order binary.ByteOrder, ifd0, exifIFD, gpsIFD []testTIFFTag) []byte {
+	ifdSize := func(tags []testTIFFTag) int {
+		size := 2 + 12*len(tags) + 4
+		for _, tag := range tags {
+			if len(tag.data) > 4 {
+				size += len(tag.data) + len(tag.data)%2
+			}
+		}
+		return size
+	}
+
+	pointer := func(tag uint16) testTIFFTag {
+		return testTIFFTag{tag: tag, typ: 4, count: 1, data: make([]byte, 4)}
+	}
+	ifd0 = append([]testTIFFTag(nil), ifd0...)
+	if exifIFD != nil {
+		ifd0 = append(ifd0, pointer(tiffTagExifIFD))
+	}
+	if gpsIFD != nil {
+		ifd0 = append(ifd0, pointer(tiffTagGPSIFD))
+	}
+
+	exifOffset := 8 + ifdSize(ifd0)
+	gpsOffset := exifOffset + ifdSize(exifIFD)
+	for i := range ifd0 {
+		switch ifd0[i].tag {
+		case tiffTagExifIFD:
+			order.PutUint32(ifd0[i].data, uint32(exifOffset))
+		case tiffTagGPSIFD:
+			order.PutUint32(ifd0[i].data, uint32(gpsOffset))
+		}
+	}
+
+	var buf bytes.Buffer
+	if order == binary.LittleEndian {
+		buf.WriteString("II")
+	} else {
+		buf.WriteString("MM")
+	}
+	binary.Write(&buf, order, uint16(42))
+	binary.Write(&buf, order, uint32(8))
+
+	for _, tags := range [][]testTIFFTag{ifd0, exifIFD, gpsIFD} {
+		if tags == nil {
+			continue
+		}
+		start := buf.Len()
+		valueOffset := start + 2 + 12*len(tags) + 4
+		var values bytes.Buffer
+
+		binary.Write(&buf, order, uint16(len(tags)))
+		for _, tag := range tags {
+			binary.Write(&buf, order, tag.tag)
+			binary.Write(&buf, order, tag.typ)
+			binary.Write(&buf, order, tag.count)
+			if len(tag.data) <= 4 {
+				field := make([]byte, 4)
+				copy(field, tag.data)
+				buf.Write(field)
+				continue
+			}
+			binary.Write(&buf, order, uint32(valueOffset+values.Len()))
+			values.Write(tag.data)
+			if len(tag.data)%2 == 1 {
+				values.WriteByte(0)
+			}
+		}
+		binary.Write(&buf, order, uint32(0))
+		buf.Write(values.Bytes())
+	}
+	return buf.Bytes()
+}
+
+// testRawTIFF builds a minimal camera RAW structure with a capture date and GPS position.
+func testRawTIFF(order binary.ByteOrder) []byte {
+	return testTIFF(order,
+		[]testTIFFTag{
+			testASCII(tiffTagMake, "NIKON CORPORATION"),
+			testASCII(tiffTagModel, "NIKON Z 6"),
+			testASCII(tiffTagDateTime, "2023:05:02 09:00:00"),
+		},
+		[]testTIFFTag{
+			testASCII(tiffTagDateTimeOriginal, "2023:05:01 14:30:00"),
+		},
+		[]testTIFFTag{
+			testASCII(gpsTagLatitudeRef, "S"),
+			testRationals(order, gpsTagLatitude, 33, 1, 52, 1, 0, 1),
+			testASCII(gpsTagLongitudeRef, "E"),
+			testRationals(order, gpsTagLongitude, 151, 1, 12, 1, 36, 1),
+		},
+	)
+}
+
+func TestMediaInfoFromTIFF(t *testing.T) {
+	want := time.Date(2023, 5, 1, 14, 30, 0, 0, time.UTC)
+	for name, order := range map[string]binary.ByteOrder{"little": binary.LittleEndian, "big": binary.BigEndian} {
+		info, err := mediaInfoFromTIFF(bytes.NewReader(testRawTIFF(order)), 0)
+		if err != nil {
+			t.Errorf("%s endian: mediaInfoFromTIFF returned error: %v", name, err)
+			continue
+		}
+		if !info.CaptureTime.Equal(want) || info.DateSource != DateSourceExifOriginal {
+			t.Errorf("%s endian: expected %s from %s, got %s from %s", name, want, DateSourceExifOriginal, info.CaptureTime, info.DateSource)
+		}
+		if info.Make != "NIKON CORPORATION" || info.Model != "NIKON Z 6" {
+			t.Errorf("%s endian: unexpected camera %q %q", name, info.Make, info.Model)
+		}
+		if info.GPS == nil || info.GPS.Latitude > -33.86 || info.GPS.Latitude < -33.87 || info.GPS.Longitude < 151.2 {
+			t.Errorf("%s endian: unexpected GPS %+v", name, info.GPS)
+		}
+	}
+}
+
+func TestMediaInfoFromTIFFDateTimeFallback(t *testing.T) {
+	data := testTIFF(binary.LittleEndian, []testTIFFTag{testASCII(tiffTagDateTime, "2020:01:02 03:04:05")}, nil, nil)
+	info, err := mediaInfoFromTIFF(bytes.NewReader(data), 0)
+	if err != nil {
+		t.Fatalf("mediaInfoFromTIFF returned error: %v", err)
+	}
+	if info.DateSource != DateSourceExifDateTime {
+		t.Errorf("Expected date source %s, got %s", DateSourceExifDateTime, info.DateSource)
+	}
+}
+
+func TestExtractTIFFRawVariants(t *testing.T) {
+	orf := testRawTIFF(binary.LittleEndian)
+	copy(orf[2:4], "RO")
+	if _, err := extractTIFF(bytes.NewReader(orf)); err != nil {
+		t.Errorf("Expected ORF header to be accepted, got %v", err)
+	}
+
+	if _, err := extractTIFF(bytes.NewReader([]byte("not a raw file at all"))); err == nil {
+		t.Errorf("Expected an error for a non-TIFF file")
+	}
+}
+
+func TestExtractRAF(t *testing.T) {
+	jpeg, err := os.ReadFile(filepath.Join(testImagesDir, "generated_sample_001.JPG"))
+	if err != nil {
+		t.Fatalf("Failed to read sample image: %v", err)
+	}
+	want, err := mediaInfoFromExif(testExifBlock(t))
+	if err != nil {
+		t.Fatalf("Failed to read sample EXIF: %v", err)
+	}
+
+	header := make([]byte, 100)
+	copy(header, rafMagic)
+	binary.BigEndian.PutUint32(header[84:88], uint32(len(header)))
+	binary.BigEndian.PutUint32(header[88:92], uint32(len(jpeg)))
+	raf := append(header, jpeg...)
+
+	info, err := extractRAF(bytes.NewReader(raf))
+	if err != nil {
+		t.Fatalf("extractRAF returned error: %v", err)
+	}
+	if !info.CaptureTime.Equal(want.CaptureTime) {
+		t.Errorf("Expected capture time %s, got %s", want.CaptureTime, info.CaptureTime)
+	}
+}
+
+func TestRegistryExtractRawFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"DSC00001.ARW", "DSC_0001.NEF", "IMG_0001.CR2", "IMG_0001.dng", "P1000001.RW2", "IMGP0001.PEF"} {
+		filePath := filepath.Join(dir, name)
+		if err := os.WriteFile(filePath, testRawTIFF(binary.LittleEndian), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		if _, err := DefaultRegistry.Extract(filePath); err != nil {
+			t.Errorf("Extract(%s) returned error: %v", name, err)
+		}
+	}
+}
+
+func TestRegistryExtractTIFFFile(t *testing.T) {
+	data := testRawTIFF(binary.BigEndian)
+	for _, name := range []string{"scan.tif", "scan.TIFF", "scan.bin"} {
+		filePath := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(filePath, data, 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		if _, err := DefaultRegistry.Extract(filePath); err != nil {
+			t.Errorf("Extract(%s) returned error: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package organizer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Time zones of capture times ---
+
+// DateSourceExifGPS is the UTC time of the GPS fix recorded with a photo.
+const DateSourceExifGPS DateSource = "exif:GPSDateTime"
+
+// maxGPSClockSkew bounds how far the camera clock may be from the GPS fix for the
+// difference to be taken as the camera's time zone offset.
+const maxGPSClockSkew = 5 * time.Minute
+
+// maxZoneOffset is the largest offset from UTC in use.
+const maxZoneOffset = 14 * time.Hour
+
+// ParseTimezone parses the -z option: "" or "local" buckets by the local time at the shooting
+// location (nil), anything else is an IANA zone name such as "Europe/Berlin", "UTC" or an offset
+// such as "+09:00".
+func ParseTimezone(s string) (*time.Location, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, "local") {
+		return nil, nil
+	}
+	if loc := parseExifOffset(s); loc != nil {
+		return loc, nil
+	}
+	loc, err := time.LoadLocation(s)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q: %w", s, err)
+	}
+	return loc, nil
+}
+
+// bucketTime returns the time whose calendar date picks a file's folder: the wall clock at the
+// shooting location, or the capture instant in BucketLocation when it is set. A camera wall clock
+// without a known zone is taken as is in both cases.
+func (o *Organizer) bucketTime(info *MediaInfo) time.Time {
+	switch {
+	case o.BucketLocation != nil && (info.Timezone != nil || info.Instant):
+		return info.CaptureTime.In(o.BucketLocation)
+	case info.Timezone != nil:
+		return info.CaptureTime.In(info.Timezone)
+	case info.Instant:
+		return info.CaptureTime.In(time.Local)
+	}
+	return info.CaptureTime
+}
+
+// zonedCandidate returns a candidate for a time parsed together with its zone, if it had one.
+// A "Z", "UTC" or "GMT" marker only says the time is an exact instant, not where it was taken.
+func zonedCandidate(t time.Time, loc *time.Location, source DateSource) DateCandidate {
+	switch {
+	case loc == nil:
+		return DateCandidate{Time: t, Source: source}
+	case loc == time.UTC || loc.String() == "GMT":
+		return DateCandidate{Time: t.In(time.Local), Instant: true, Source: source}
+	}
+	return DateCandidate{Time: t, Timezone: loc, Source: source}
+}
+
+// exifDateCandidate builds a candidate from an EXIF date and its SubSecTime and OffsetTime companions.
+func exifDateCandidate(value, subSec, offset string, source DateSource) (DateCandidate, bool) {
+	t, ok := parseExifTime(value)
+	if !ok {
+		return DateCandidate{}, false
+	}
+	t = t.Add(parseSubSec(subSec))
+
+	loc := parseExifOffset(offset)
+	if loc == nil {
+		return DateCandidate{Time: t, Source: source}, true
+	}
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+	return DateCandidate{Time: t, Timezone: loc, Source: source}, true
+}
+
+// parseSubSec parses a SubSecTime value, the decimal digits of a fraction of a second.
+func parseSubSec(s string) time.Duration {
+	s = strings.TrimSpace(strings.TrimRight(s, "\x00"))
+	if s == "" || strings.Trim(s, "0123456789") != "" {
+		return 0
+	}
+	if len(s) > 9 {
+		s = s[:9]
+	}
+	ns, _ := strconv.Atoi(s + strings.Repeat("0", 9-len(s)))
+	return time.Duration(ns)
+}
+
+// parseExifOffset parses an OffsetTime value such as "+09:00" into a fixed zone.
+func parseExifOffset(s string) *time.Location {
+	s = strings.TrimSpace(strings.TrimRight(s, "\x00"))
+	for _, layout := range []string{"-07:00", "-0700"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			_, offset := t.Zone()
+			return offsetZone(time.Duration(offset) * time.Second)
+		}
+	}
+	return nil
+}
+
+// offsetZone returns a fixed zone named after its offset, such as "+09:00".
+func offsetZone(offset time.Duration) *time.Location {
+	sign, abs := '+', offset
+	if offset < 0 {
+		sign, abs = '-', -offset
+	}
+	name := fmt.Sprintf("%c%02d:%02d", sign, int(abs.Hours()), int(abs.Minutes())%60)
+	return time.FixedZone(name, int(offset.Seconds()))
+}
+
+// gpsDateTime combines a GPSDateStamp ("2006:01:02") with a GPSTimeStamp in hours into a UTC time.
+func gpsDateTime(dateStamp string, hours float64) (time.Time, bool) {
+	dateStamp = strings.TrimSpace(strings.TrimRight(dateStamp, "\x00"))
+	date, err := time.Parse("2006:01:02", dateStamp)
+	if err != nil || hours < 0 || hours >= 24 {
+		return time.Time{}, false
+	}
+	return date.Add(time.Duration(hours * float64(time.Hour))).Round(time.Second), true
+}
+
+// applyGPSTime adds the GPS fix time to the candidates. When DateTimeOriginal has no offset,
+// the difference between the camera clock and the GPS fix gives the zone it was taken in.
+func applyGPSTime(candidates []DateCandidate, gpsTime time.Time) []DateCandidate {
+	var loc *time.Location
+	for _, candidate := range candidates {
+		if candidate.Source != DateSourceExifOriginal || candidate.Timezone != nil {
+			continue
+		}
+		diff := candidate.Time.Sub(gpsTime)
+		offset := diff.Round(15 * time.Minute)
+		if skew := diff - offset; skew <= maxGPSClockSkew && skew >= -maxGPSClockSkew &&
+			offset <= maxZoneOffset && offset >= -maxZoneOffset {
+			loc = offsetZone(offset)
+		}
+		break
+	}
+
+	if loc != nil {
+		for i, candidate := range candidates {
+			if candidate.Timezone == nil && !candidate.Instant {
+				t := candidate.Time
+				candidates[i].Time = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+				candidates[i].Timezone = loc
+			}
+		}
+		return append(candidates, DateCandidate{Time: gpsTime.In(loc), Timezone: loc, Source: DateSourceExifGPS})
+	}
+	return append(candidates, DateCandidate{Time: gpsTime.In(time.Local), Instant: true, Source: DateSourceExifGPS})
+}
@@ -0,0 +1,138 @@
+package organizer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestExifOffsetAndSubSec(t *testing.T) {
+	order := binary.BigEndian
+	data := testTIFF(order,
+		[]testTIFFTag{testASCII(tiffTagMake, "SONY")},
+		[]testTIFFTag{
+			testASCII(tiffTagDateTimeOriginal, "2023:05:01 23:30:00"),
+			testASCII(tiffTagSubSecTimeOriginal, "45"),
+			testASCII(tiffTagOffsetTimeOriginal, "+09:00"),
+		},
+		nil)
+	want := time.Date(2023, 5, 1, 14, 30, 0, 450*int(time.Millisecond), time.UTC)
+
+	native, err := extractTIFF(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("extractTIFF returned error: %v", err)
+	}
+	exifInfo, err := mediaInfoFromExif(data)
+	if err != nil {
+		t.Fatalf("mediaInfoFromExif returned error: %v", err)
+	}
+	for name, info := range map[string]*MediaInfo{"native": native, "go-exif": exifInfo} {
+		if !info.CaptureTime.Equal(want) {
+			t.Errorf("%s: expected capture instant %s, got %s", name, want, info.CaptureTime)
+		}
+		if info.Timezone == nil || info.Timezone.String() != "+09:00" {
+			t.Errorf("%s: expected zone +09:00, got %v", name, info.Timezone)
+		}
+	}
+}
+
+func TestExifGPSTimeOffset(t *testing.T) {
+	order := binary.LittleEndian
+	data := testTIFF(order,
+		[]testTIFFTag{testASCII(tiffTagMake, "Canon")},
+		[]testTIFFTag{testASCII(tiffTagDateTimeOriginal, "2023:05:01 20:31:10")},
+		[]testTIFFTag{
+			testASCII(gpsTagDateStamp, "2023:05:01"),
+			// 18:30:00 UTC; the camera clock runs 70 seconds fast.
+			testRationals(order, gpsTagTimeStamp, 18, 1, 30, 1, 0, 1),
+		})
+
+	native, err := extractTIFF(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("extractTIFF returned error: %v", err)
+	}
+	exifInfo, err := mediaInfoFromExif(data)
+	if err != nil {
+		t.Fatalf("mediaInfoFromExif returned error: %v", err)
+	}
+	for name, info := range map[string]*MediaInfo{"native": native, "go-exif": exifInfo} {
+		if info.Timezone == nil || info.Timezone.String() != "+02:00" {
+			t.Fatalf("%s: expected zone +02:00 from the GPS time, got %v", name, info.Timezone)
+		}
+		if want := time.Date(2023, 5, 1, 18, 31, 10, 0, time.UTC); !info.CaptureTime.Equal(want) {
+			t.Errorf("%s: expected capture instant %s, got %s", name, want, info.CaptureTime)
+		}
+		last := info.Dates[len(info.Dates)-1]
+		if last.Source != DateSourceExifGPS || !last.Time.Equal(time.Date(2023, 5, 1, 18, 30, 0, 0, time.UTC)) {
+			t.Errorf("%s: expected GPS date candidate, got %+v", name, last)
+		}
+	}
+
+	// A stale GPS fix does not give a zone.
+	candidates := applyGPSTime([]DateCandidate{{Time: time.Date(2023, 5, 1, 20, 31, 10, 0, time.UTC), Source: DateSourceExifOriginal}},
+		time.Date(2023, 5, 1, 18, 10, 0, 0, time.UTC))
+	if candidates[0].Timezone != nil {
+		t.Errorf("Expected no zone from a stale GPS fix, got %v", candidates[0].Timezone)
+	}
+}
+
+func TestBucketTime(t *testing.T) {
+	tokyo := offsetZone(9 * time.Hour)
+	berlin, err := ParseTimezone("+02:00")
+	if err != nil {
+		t.Fatalf("ParseTimezone returned error: %v", err)
+	}
+
+	// 23:30 in Tokyo is 16:30 in Berlin the same day, 01:30 in Tokyo is still the previous day in Berlin.
+	evening := &MediaInfo{CaptureTime: time.Date(2023, 5, 1, 23, 30, 0, 0, tokyo), Timezone: tokyo}
+	night := &MediaInfo{CaptureTime: time.Date(2023, 5, 2, 1, 30, 0, 0, tokyo), Timezone: tokyo}
+	// A phone video header holds 23:30 UTC, which is already the next day in Tokyo.
+	video := &MediaInfo{CaptureTime: time.Date(2023, 5, 1, 23, 30, 0, 0, time.UTC), Instant: true}
+	// A camera wall clock without a zone is never shifted.
+	wallClock := &MediaInfo{CaptureTime: time.Date(2023, 5, 1, 23, 30, 0, 0, time.UTC)}
+
+	tests := []struct {
+		location *time.Location
+		info     *MediaInfo
+		want     string
+	}{
+		{nil, evening, "20230501"},
+		{nil, night, "20230502"},
+		{berlin, evening, "20230501"},
+		{berlin, night, "20230501"},
+		{tokyo, video, "20230502"},
+		{berlin, wallClock, "20230501"},
+		{tokyo, wallClock, "20230501"},
+	}
+	org := NewOrganizer("", "ymd", "generated", "seq", "0", "copy")
+	for i, tt := range tests {
+		org.BucketLocation = tt.location
+		if got := org.bucketTime(tt.info).Format("20060102"); got != tt.want {
+			t.Errorf("Case %d: expected folder %s, got %s", i, tt.want, got)
+		}
+	}
+
+	for _, s := range []string{"", "local"} {
+		if loc, err := ParseTimezone(s); loc != nil || err != nil {
+			t.Errorf("ParseTimezone(%q) = %v, %v; want nil, nil", s, loc, err)
+		}
+	}
+	if loc, err := ParseTimezone("UTC"); err != nil || loc != time.UTC {
+		t.Errorf("ParseTimezone(UTC) = %v, %v", loc, err)
+	}
+	if _, err := ParseTimezone("Mars/Olympus_Mons"); err == nil {
+		t.Errorf("Expected error for an unknown zone")
+	}
+}
+
+func TestZonedCandidate(t *testing.T) {
+	utc, _, _ := parseQuickTimeDate("2023-05-01T23:30:00Z")
+	if candidate := zonedCandidate(utc, time.UTC, DateSourceQuickTimeCreationDate); candidate.Timezone != nil || !candidate.Instant {
+		t.Errorf("Expected a Z time to be an instant without zone, got %+v", candidate)
+	}
+	local, loc, _ := parseQuickTimeDate("2023-05-01T23:30:00+0200")
+	if candidate := zonedCandidate(local, loc, DateSourceQuickTimeCreationDate); candidate.Timezone == nil || candidate.Instant {
+		t.Errorf("Expected an offset time to keep its zone, got %+v", candidate)
+	}
+}
@@ -0,0 +1,74 @@
+package organizer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// --- WebP capture dates ---
+
+// extractWebP reads capture metadata from the EXIF and "XMP " chunks of a RIFF/WebP image.
+func extractWebP(r io.ReadSeeker) (*MediaInfo, error) {
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(r, header); err != nil || string(header[0:4]) != "RIFF" || string(header[8:12]) != "WEBP" {
+		return nil, ErrNoCaptureDate
+	}
+	end := int64(binary.LittleEndian.Uint32(header[4:8])) + 8
+
+	info := &MediaInfo{}
+	var candidates []DateCandidate
+	chunk := make([]byte, 8)
+
+	for offset := int64(12); offset+8 <= end; {
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			break
+		}
+		fourCC := string(chunk[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+
+		if size <= maxPNGTextChunk {
+			switch fourCC {
+			case "VP8X", "EXIF", "XMP ":
+				data := make([]byte, size)
+				if _, err := io.ReadFull(r, data); err != nil {
+					break
+				}
+				candidates = append(candidates, readWebPChunk(fourCC, data, info)...)
+			}
+		}
+
+		// Chunks are padded to an even size.
+		offset += 8 + size + size%2
+	}
+
+	return info, info.setDates(candidates)
+}
+
+// readWebPChunk reads one metadata chunk, filling the camera fields of info and returning any dates found.
+func readWebPChunk(fourCC string, data []byte, info *MediaInfo) []DateCandidate {
+	switch fourCC {
+	case "VP8X":
+		// The canvas size is stored minus one in two 24-bit little-endian fields.
+		if len(data) >= 10 {
+			info.Width = int(uint32(data[4])|uint32(data[5])<<8|uint32(data[6])<<16) + 1
+			info.Height = int(uint32(data[7])|uint32(data[8])<<8|uint32(data[9])<<16) + 1
+		}
+	case "EXIF":
+		exifInfo, _ := mediaInfoFromExif(bytes.TrimPrefix(data, []byte("Exif\x00\x00")))
+		if exifInfo == nil {
+			return nil
+		}
+		mergeCameraInfo(info, exifInfo)
+		return exifInfo.Dates
+	case "XMP ":
+		if xmpInfo, _ := mediaInfoFromXMP(data); xmpInfo != nil {
+			mergeCameraInfo(info, xmpInfo)
+		}
+		return parseXMPDates(data)
+	}
+	return nil
+}
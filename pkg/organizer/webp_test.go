@@ -0,0 +1,85 @@
+package organizer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testRIFFChunk builds a little-endian RIFF chunk padded to an even size.
+func testRIFFChunk(fourCC string, data []byte) []byte {
+	chunk := make([]byte, 8, 9+len(data))
+	copy(chunk[0:4], fourCC)
+	binary.LittleEndian.PutUint32(chunk[4:8], uint32(len(data)))
+	chunk = append(chunk, data...)
+	if len(data)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+// testWebP builds an extended WebP file from metadata chunks.
+func testWebP(chunks ...[]byte) []byte {
+	vp8x := make([]byte, 10)
+	vp8x[4], vp8x[5] = 0x7F, 0x02 // width 640
+	vp8x[7], vp8x[8] = 0xDF, 0x01 // height 480
+	body := bytes.Join(append([][]byte{[]byte("WEBP"), testRIFFChunk("VP8X", vp8x), testRIFFChunk("VP8 ", make([]byte, 11))}, chunks...), nil)
+
+	header := make([]byte, 8)
+	copy(header[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:8], uint32(len(body)))
+	return append(header, body...)
+}
+
+func TestExtractWebPExif(t *testing.T) {
+	want, err := mediaInfoFromExif(testExifBlock(t))
+	if err != nil {
+		t.Fatalf("Failed to read sample EXIF: %v", err)
+	}
+
+	exifChunk := append([]byte("Exif\x00\x00"), testExifBlock(t)...)
+	info, err := extractWebP(bytes.NewReader(testWebP(testRIFFChunk("EXIF", exifChunk))))
+	if err != nil {
+		t.Fatalf("extractWebP returned error: %v", err)
+	}
+	if !info.CaptureTime.Equal(want.CaptureTime) {
+		t.Errorf("Expected capture time %s, got %s", want.CaptureTime, info.CaptureTime)
+	}
+	if info.Width != 640 || info.Height != 480 {
+		t.Errorf("Expected VP8X size 640x480, got %dx%d", info.Width, info.Height)
+	}
+}
+
+func TestExtractWebPXMP(t *testing.T) {
+	xmp := testXMPPacket(`xmp:CreateDate="2023-05-01T14:30:00">`)
+	info, err := extractWebP(bytes.NewReader(testWebP(testRIFFChunk("XMP ", []byte(xmp)))))
+	if err != nil {
+		t.Fatalf("extractWebP returned error: %v", err)
+	}
+	if info.DateSource != DateSourceXMPCreateDate {
+		t.Errorf("Expected date source %s, got %s", DateSourceXMPCreateDate, info.DateSource)
+	}
+}
+
+func TestExtractWebPWithoutMetadata(t *testing.T) {
+	if _, err := extractWebP(bytes.NewReader(testWebP())); err == nil {
+		t.Errorf("Expected an error for a WebP without metadata")
+	}
+}
+
+func TestRegistryExtractWebPFile(t *testing.T) {
+	xmp := testXMPPacket(`exif:DateTimeOriginal="2023-05-01T14:30:00">`)
+	data := testWebP(testRIFFChunk("XMP ", []byte(xmp)))
+	filePath := filepath.Join(t.TempDir(), "IMG-20230501-WA0001.webp")
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		t.Fatalf("Failed to write WebP: %v", err)
+	}
+	if _, err := DefaultRegistry.Extract(filePath); err != nil {
+		t.Errorf("Extract returned error: %v", err)
+	}
+	if got := sniffContentType(data[:32]); got != "image/webp" {
+		t.Errorf("Expected image/webp from header, got %s", got)
+	}
+}
@@ -0,0 +1,189 @@
+package organizer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// --- XMP packet parsing ---
+
+// Date sources read from XMP packets.
+const (
+	DateSourceXMPOriginal    DateSource = "xmp:DateTimeOriginal"
+	DateSourceXMPCreateDate  DateSource = "xmp:CreateDate"
+	DateSourceXMPDateCreated DateSource = "xmp:photoshop:DateCreated"
+)
+
+// xmpDatePattern matches date properties written either as attributes or as elements.
+var xmpDatePattern = regexp.MustCompile(`(exif|exifEX|xmp|photoshop):(DateTimeOriginal|CreateDate|DateCreated|DateTimeDigitized)\s*(?:=\s*["']([^"']*)["']|>\s*([^<]*?)\s*<)`)
+
+// xmpDateLayouts lists the ISO 8601 variants XMP dates are written in.
+var xmpDateLayouts = []string{
+	"2006-01-02T15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// xmpTextPattern matches simple text properties used for the camera make, model and lens.
+var xmpTextPattern = regexp.MustCompile(`(?:tiff|exifEX|aux):(Make|Model|LensModel|Lens)\s*(?:=\s*["']([^"']*)["']|>\s*([^<]*?)\s*<)`)
+
+// parseXMPTime parses an XMP date, keeping the offset when one is given.
+func parseXMPTime(s string) (time.Time, *time.Location, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range xmpDateLayouts {
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			continue
+		}
+		if strings.HasSuffix(layout, "Z07:00") {
+			return t, t.Location(), true
+		}
+		return t, nil, true
+	}
+	// Some writers use the EXIF layout inside XMP.
+	if t, ok := parseExifTime(s); ok {
+		return t, nil, true
+	}
+	return time.Time{}, nil, false
+}
+
+// parseXMPDates returns the capture date candidates found in an XMP packet.
+func parseXMPDates(packet []byte) []DateCandidate {
+	var candidates []DateCandidate
+	for _, m := range xmpDatePattern.FindAllSubmatch(packet, -1) {
+		value := string(m[3])
+		if value == "" {
+			value = string(m[4])
+		}
+		t, loc, ok := parseXMPTime(value)
+		if !ok {
+			continue
+		}
+
+		var source DateSource
+		switch prefix, name := string(m[1]), string(m[2]); {
+		case name == "DateTimeOriginal":
+			source = DateSourceXMPOriginal
+		case prefix == "photoshop":
+			source = DateSourceXMPDateCreated
+		default:
+			source = DateSourceXMPCreateDate
+		}
+		candidates = append(candidates, zonedCandidate(t, loc, source))
+	}
+	return candidates
+}
+
+// mediaInfoFromXMP builds a MediaInfo from the best date and the camera found in an XMP packet.
+func mediaInfoFromXMP(packet []byte) (*MediaInfo, error) {
+	info := &MediaInfo{}
+	for _, m := range xmpTextPattern.FindAllSubmatch(packet, -1) {
+		value := string(m[2])
+		if value == "" {
+			value = string(m[3])
+		}
+		if value == "" {
+			// Closing tags match the element form with an empty value.
+			continue
+		}
+		switch string(m[1]) {
+		case "Make":
+			info.Make = value
+		case "Model":
+			info.Model = value
+		default:
+			if info.Lens == "" {
+				info.Lens = value
+			}
+		}
+	}
+
+	return info, info.setDates(parseXMPDates(packet))
+}
+
+// --- XMP sidecars ---
+
+// Date sources read from XMP sidecar files.
+const (
+	DateSourceSidecarOriginal    DateSource = "xmp-sidecar:DateTimeOriginal"
+	DateSourceSidecarCreateDate  DateSource = "xmp-sidecar:CreateDate"
+	DateSourceSidecarDateCreated DateSource = "xmp-sidecar:photoshop:DateCreated"
+)
+
+// Values of Organizer.XMPPrecedence.
+const (
+	XMPPrecedenceSidecar  = "sidecar"  // sidecar dates win over embedded dates of the same kind
+	XMPPrecedenceEmbedded = "embedded" // embedded dates win; sidecars only fill gaps
+)
+
+// ParseXMPPrecedence checks an XMP precedence; "" is XMPPrecedenceSidecar.
+func ParseXMPPrecedence(s string) (string, error) {
+	switch s {
+	case "":
+		return XMPPrecedenceSidecar, nil
+	case XMPPrecedenceSidecar, XMPPrecedenceEmbedded:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown XMP precedence %q (sidecar/embedded)", s)
+}
+
+// sidecarSources maps the date sources of an XMP packet to their sidecar variants.
+var sidecarSources = map[DateSource]DateSource{
+	DateSourceXMPOriginal:    DateSourceSidecarOriginal,
+	DateSourceXMPCreateDate:  DateSourceSidecarCreateDate,
+	DateSourceXMPDateCreated: DateSourceSidecarDateCreated,
+}
+
+// maxXMPSidecar bounds the size of an XMP sidecar that is read into memory.
+const maxXMPSidecar = 4 * 1024 * 1024
+
+// findXMPSidecar returns the path of the XMP sidecar of a media file, or "".
+// darktable and digiKam write "file.ext.xmp", Lightroom and Capture One write "file.xmp".
+func findXMPSidecar(filePath string) string {
+	stem := strings.TrimSuffix(filePath, filepath.Ext(filePath))
+	for _, candidate := range []string{filePath + ".xmp", filePath + ".XMP", stem + ".xmp", stem + ".XMP"} {
+		if stat, err := os.Stat(candidate); err == nil && stat.Mode().IsRegular() && stat.Size() <= maxXMPSidecar {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// readXMPSidecar reads the XMP sidecar of a media file, if it has one.
+func readXMPSidecar(filePath string) (string, *MediaInfo, bool) {
+	sidecar := findXMPSidecar(filePath)
+	if sidecar == "" {
+		return "", nil, false
+	}
+	packet, err := os.ReadFile(sidecar)
+	if err != nil {
+		return "", nil, false
+	}
+
+	info, _ := mediaInfoFromXMP(packet)
+	for i := range info.Dates {
+		info.Dates[i].Source = sidecarSources[info.Dates[i].Source]
+	}
+	return sidecar, info, true
+}
+
+// mergeXMPSidecar adds the dates and camera of an XMP sidecar to the embedded metadata.
+// With sidecar precedence, sidecar dates come before embedded dates of the same kind.
+func mergeXMPSidecar(info, sidecarInfo *MediaInfo, precedence string) error {
+	var candidates []DateCandidate
+	if precedence == XMPPrecedenceEmbedded {
+		candidates = append(append(candidates, info.Dates...), sidecarInfo.Dates...)
+	} else {
+		candidates = append(append(candidates, sidecarInfo.Dates...), info.Dates...)
+	}
+	mergeCameraInfo(info, sidecarInfo)
+	return info.setDates(candidates)
+}
@@ -0,0 +1,110 @@
+package organizer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseXMPDates(t *testing.T) {
+	packet := []byte(testXMPPacket(`xmp:CreateDate="2023-05-01T10:00:00"
+		photoshop:DateCreated="2023-05-01">
+		<exif:DateTimeOriginal>2023-05-01T14:30:00.120+09:00</exif:DateTimeOriginal>
+		<tiff:Make>FUJIFILM</tiff:Make><tiff:Model>X-T4</tiff:Model>`))
+
+	candidates := parseXMPDates(packet)
+	if len(candidates) != 3 {
+		t.Fatalf("Expected 3 date candidates, got %d", len(candidates))
+	}
+
+	info, err := mediaInfoFromXMP(packet)
+	if err != nil {
+		t.Fatalf("mediaInfoFromXMP returned error: %v", err)
+	}
+	want := time.Date(2023, 5, 1, 5, 30, 0, 120000000, time.UTC)
+	if info.DateSource != DateSourceXMPOriginal || !info.CaptureTime.Equal(want) {
+		t.Errorf("Expected %s from %s, got %s from %s", want, DateSourceXMPOriginal, info.CaptureTime, info.DateSource)
+	}
+	if info.Make != "FUJIFILM" || info.Model != "X-T4" {
+		t.Errorf("Expected FUJIFILM X-T4, got %q %q", info.Make, info.Model)
+	}
+}
+
+func TestMediaInfoFromXMPWithoutDate(t *testing.T) {
+	if _, err := mediaInfoFromXMP([]byte(testXMPPacket(`dc:format="image/jpeg">`))); err == nil {
+		t.Errorf("Expected an error for an XMP packet without dates")
+	}
+}
+
+func TestXMPSidecarPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, "DSC0001.xyz", "DSC0002.xyz", "DSC0003.xyz")
+	sidecars := map[string]string{
+		"DSC0001.xyz.xmp": `exif:DateTimeOriginal="2023-05-01T14:30:00" tiff:Make="NIKON CORPORATION">`,
+		"DSC0002.xmp":     `xmp:CreateDate="2023-05-02T09:00:00">`,
+	}
+	for name, body := range sidecars {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(testXMPPacket(body)), 0644); err != nil {
+			t.Fatalf("Failed to write sidecar: %v", err)
+		}
+	}
+
+	embedded := time.Date(2023, 4, 30, 12, 0, 0, 0, time.UTC)
+	corrected := time.Date(2023, 5, 1, 14, 30, 0, 0, time.UTC)
+	created := time.Date(2023, 5, 2, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		file       string
+		precedence string
+		want       time.Time
+		source     DateSource
+	}{
+		{"DSC0001.xyz", "", corrected, DateSourceSidecarOriginal},
+		{"DSC0001.xyz", XMPPrecedenceEmbedded, embedded, DateSourceExifOriginal},
+		// A sidecar CreateDate does not override an embedded DateTimeOriginal.
+		{"DSC0002.xyz", XMPPrecedenceSidecar, embedded, DateSourceExifOriginal},
+		{"DSC0003.xyz", XMPPrecedenceSidecar, embedded, DateSourceExifOriginal},
+	}
+
+	org := NewOrganizer(dir, "ymd", "generated", "seq", "0", "copy")
+	org.Extractors = testChainRegistry(DateCandidate{Time: embedded, Source: DateSourceExifOriginal})
+	for _, tt := range tests {
+		org.XMPPrecedence = tt.precedence
+		info, err := org.readMediaInfo(filepath.Join(dir, tt.file))
+		if err != nil {
+			t.Fatalf("readMediaInfo(%s) returned error: %v", tt.file, err)
+		}
+		if info.DateSource != tt.source || !info.CaptureTime.Equal(tt.want) {
+			t.Errorf("%s with precedence %q: got %s from %s, want %s from %s", tt.file, tt.precedence, info.CaptureTime, info.DateSource, tt.want, tt.source)
+		}
+		// The default chain dates the file the same way, through the xmp step or the EXIF ones.
+		if info, err = org.resolveDate(filepath.Join(dir, tt.file)); err != nil || info.DateSource != tt.source {
+			t.Errorf("%s with precedence %q: resolved %v from %s, want %s", tt.file, tt.precedence, err, info.DateSource, tt.source)
+		}
+	}
+
+	// Without embedded dates the sidecar dates the file, and it travels with the media.
+	org.Extractors = testChainRegistry()
+	org.DateChain = []string{DateStepExifOriginal, DateStepXMP}
+	info, err := org.resolveDate(filepath.Join(dir, "DSC0002.xyz"))
+	if err != nil {
+		t.Fatalf("resolveDate returned error: %v", err)
+	}
+	if info.DateSource != DateSourceSidecarCreateDate || !info.CaptureTime.Equal(created) {
+		t.Errorf("Expected %s from the sidecar, got %s from %s", created, info.CaptureTime, info.DateSource)
+	}
+	org.DateChain = []string{DateStepExifOriginal, DateStepExifCreate}
+	if _, err := org.resolveDate(filepath.Join(dir, "DSC0002.xyz")); !errors.Is(err, ErrNoCaptureDate) {
+		t.Errorf("Expected sidecar dates to be left to the xmp step, got %v", err)
+	}
+	if len(info.Sidecars) != 1 || filepath.Base(info.Sidecars[0]) != "DSC0002.xmp" {
+		t.Errorf("Expected the sidecar to be recorded, got %v", info.Sidecars)
+	}
+	if got := sidecarPath(info.Sidecars[0], filepath.Join(dir, "DSC0002.xyz"), "/out/20230502/DSC0002.xyz"); got != "/out/20230502/DSC0002.xmp" {
+		t.Errorf("Expected the sidecar to keep its name form, got %s", got)
+	}
+	if got := sidecarPath(filepath.Join(dir, "DSC0001.xyz.xmp"), filepath.Join(dir, "DSC0001.xyz"), "/out/20230501/DSC0001.xyz"); got != "/out/20230501/DSC0001.xyz.xmp" {
+		t.Errorf("Expected the sidecar to keep its name form, got %s", got)
+	}
+}